@@ -0,0 +1,538 @@
+// Command admin menjalankan tugas administrasi dan pemeliharaan terhadap database yang sama
+// dengan server.
+//
+//	admin create-admin [-config file] [-dry-run] -email EMAIL [-name NAME]
+//	admin reset-password [-config file] [-dry-run] -email EMAIL
+//	admin seed [-config file] [-dry-run] [-products N] [-seed N] [-sample N]
+//	admin ensure-indexes [-config file] [-dry-run]
+//	admin purge [-config file] [-dry-run]
+//	admin migrate [-config file] [-dry-run] [-force] [-stale-after DURATION]
+//
+// Password dibaca dari baris pertama stdin sehingga tidak tersimpan di riwayat shell.
+// Dengan -dry-run tidak ada yang ditulis; perintah hanya melaporkan apa yang akan dilakukan.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/config"
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const usage = `Usage:
+  admin create-admin [-config file] [-dry-run] -email EMAIL [-name NAME]
+  admin reset-password [-config file] [-dry-run] -email EMAIL
+  admin seed [-config file] [-dry-run] [-products N] [-seed N] [-sample N]
+  admin ensure-indexes [-config file] [-dry-run]
+  admin purge [-config file] [-dry-run]
+  admin migrate [-config file] [-dry-run] [-force] [-stale-after DURATION]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	commands := map[string]func(context.Context, []string) error{
+		"create-admin":   runCreateAdmin,
+		"reset-password": runResetPassword,
+		"seed":           runSeed,
+		"ensure-indexes": runEnsureIndexes,
+		"purge":          runPurge,
+		"migrate":        runMigrate,
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	err := run(ctx, os.Args[2:])
+	var exit exitCode
+	if errors.As(err, &exit) {
+		os.Exit(int(exit))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// exitCode menghentikan program dengan kode tertentu tanpa pesan tambahan
+type exitCode int
+
+func (e exitCode) Error() string { return fmt.Sprintf("exit %d", int(e)) }
+
+// commonFlags adalah flag yang dimiliki semua perintah
+type commonFlags struct {
+	configFile string
+	dryRun     bool
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	common := &commonFlags{}
+	fs.StringVar(&common.configFile, "config", os.Getenv("CONFIG_FILE"), "path file konfigurasi YAML atau JSON")
+	fs.BoolVar(&common.dryRun, "dry-run", false, "laporkan saja tanpa menulis ke database")
+	return fs, common
+}
+
+// connect memuat konfigurasi dan membuka database
+func connect(configFile string) (*mongo.Database, func(), error) {
+	var args []string
+	if configFile != "" {
+		args = []string{"-config", configFile}
+	}
+	cfg, err := config.Load(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := config.ConnectDB(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client.Database(cfg.DBName), func() { client.Disconnect(context.Background()) }, nil
+}
+
+// wouldPrefix menandai keluaran -dry-run
+func wouldPrefix(dryRun bool) string {
+	if dryRun {
+		return "[dry run] would "
+	}
+	return ""
+}
+
+// readPassword membaca password dari baris pertama stdin
+func readPassword(r io.Reader) (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if len(password) < models.MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", models.MinPasswordLength)
+	}
+	return password, nil
+}
+
+func findUser(ctx context.Context, users *mongo.Collection, email string) (*models.User, error) {
+	var user models.User
+	err := users.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// runCreateAdmin membuat pengguna admin baru, atau menaikkan pengguna yang sudah ada menjadi admin
+func runCreateAdmin(ctx context.Context, args []string) error {
+	fs, common := newFlagSet("create-admin")
+	email := fs.String("email", "", "email admin")
+	name := fs.String("name", "Admin", "nama admin (hanya untuk pengguna baru)")
+	fs.Parse(args)
+	*email = models.NormalizeEmail(*email)
+	if *email == "" || fs.NArg() != 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitCode(2)
+	}
+
+	db, disconnect, err := connect(common.configFile)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	users := db.Collection("users")
+
+	existing, err := findUser(ctx, users, *email)
+	if err != nil {
+		return err
+	}
+	prefix := wouldPrefix(common.dryRun)
+	if existing != nil {
+		if existing.Role == models.RoleAdmin {
+			fmt.Printf("%s is already an admin\n", *email)
+			return nil
+		}
+		fmt.Printf("%spromote %s from %s to admin\n", prefix, *email, existing.Role)
+		if common.dryRun {
+			return nil
+		}
+		_, err := users.UpdateOne(ctx, bson.M{"_id": existing.ID},
+			bson.M{"$set": bson.M{"role": models.RoleAdmin, "email_verified": true}})
+		return err
+	}
+
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%screate admin %s\n", prefix, *email)
+	if common.dryRun {
+		return nil
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := models.EnsureUserIndexes(ctx, users); err != nil {
+		return fmt.Errorf("index users: %w", err)
+	}
+	_, err = users.InsertOne(ctx, models.User{
+		ID:            primitive.NewObjectID(),
+		Name:          strings.TrimSpace(*name),
+		Email:         *email,
+		Password:      string(hashed),
+		Role:          models.RoleAdmin,
+		EmailVerified: true,
+	})
+	return err
+}
+
+// runResetPassword mengganti password pengguna dan mencabut semua sesinya
+func runResetPassword(ctx context.Context, args []string) error {
+	fs, common := newFlagSet("reset-password")
+	email := fs.String("email", "", "email pengguna")
+	fs.Parse(args)
+	*email = models.NormalizeEmail(*email)
+	if *email == "" || fs.NArg() != 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitCode(2)
+	}
+
+	db, disconnect, err := connect(common.configFile)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	user, err := findUser(ctx, db.Collection("users"), *email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", *email)
+	}
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%sreset password of %s and sign out all sessions\n", wouldPrefix(common.dryRun), *email)
+	if common.dryRun {
+		return nil
+	}
+	return models.SetPassword(ctx, db.Collection("users"), db.Collection("action_tokens"), user.ID, password)
+}
+
+// Data demo untuk perintah seed
+var (
+	demoRegions    = []string{"Jakarta", "Bandung", "Surabaya", "Yogyakarta", "Medan", "Makassar", "Denpasar"}
+	demoCategories = []string{"Makanan", "Minuman", "Camilan", "Bumbu Dapur"}
+	demoNouns      = []string{"Kopi", "Teh", "Keripik", "Sambal", "Rendang", "Kerupuk", "Dodol", "Madu", "Rempeyek", "Bakpia"}
+	demoAdjectives = []string{"Original", "Pedas", "Manis", "Gurih", "Premium", "Spesial", "Asli", "Super"}
+	demoTags       = []string{"promo", "lokal", "halal", "oleh-oleh", "terlaris", "baru"}
+)
+
+// demoSKUPrefix menandai produk demo sehingga seed bisa dijalankan ulang tanpa duplikasi
+const demoSKUPrefix = "DEMO-"
+
+// runSeed mengisi region, kategori dan produk demo. Dokumen yang sudah ada tidak diubah.
+func runSeed(ctx context.Context, args []string) error {
+	fs, common := newFlagSet("seed")
+	count := fs.Int("products", 25, "jumlah produk demo")
+	seed := fs.Int64("seed", 1, "seed acak; seed yang sama menghasilkan produk yang sama")
+	sample := fs.Uint("sample", 3, "jumlah produk acak yang ditampilkan setelah seed")
+	fs.Parse(args)
+	if *count < 0 || fs.NArg() != 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitCode(2)
+	}
+
+	db, disconnect, err := connect(common.configFile)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	if !common.dryRun {
+		if err := models.EnsureIndexes(ctx, db); err != nil {
+			return err
+		}
+	}
+	prefix := wouldPrefix(common.dryRun)
+
+	regions := make([]seedDoc, len(demoRegions))
+	for i, name := range demoRegions {
+		regions[i] = seedDoc{filter: bson.M{"name": name}, doc: models.Region{Name: name}}
+	}
+	inserted, err := seedMissing(ctx, db.Collection("regions"), regions, common.dryRun)
+	if err != nil {
+		return fmt.Errorf("seed regions: %w", err)
+	}
+	fmt.Printf("%sinsert %d of %d regions\n", prefix, inserted, len(regions))
+
+	now := time.Now()
+	categories := make([]seedDoc, len(demoCategories))
+	for i, name := range demoCategories {
+		slug := models.Slugify(name)
+		categories[i] = seedDoc{filter: bson.M{"slug": slug}, doc: models.Category{
+			ID: primitive.NewObjectID(), Name: name, Slug: slug, Ancestors: []primitive.ObjectID{},
+			SortOrder: i, CreatedAt: now, UpdatedAt: now,
+		}}
+	}
+	inserted, err = seedMissing(ctx, db.Collection("categories"), categories, common.dryRun)
+	if err != nil {
+		return fmt.Errorf("seed categories: %w", err)
+	}
+	fmt.Printf("%sinsert %d of %d categories\n", prefix, inserted, len(categories))
+
+	// Produk memakai ID kategori yang tersimpan, bukan ID baru di atas yang mungkin tidak terpakai
+	var categoryIDs []primitive.ObjectID
+	if !common.dryRun {
+		slugs := make([]string, len(demoCategories))
+		for i, name := range demoCategories {
+			slugs[i] = models.Slugify(name)
+		}
+		ids, err := db.Collection("categories").Distinct(ctx, "_id", bson.M{"slug": bson.M{"$in": slugs}})
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if oid, ok := id.(primitive.ObjectID); ok {
+				categoryIDs = append(categoryIDs, oid)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	products := make([]seedDoc, *count)
+	for i := range products {
+		product := demoProduct(rng, i, categoryIDs, now)
+		products[i] = seedDoc{filter: bson.M{"sku": product.SKU}, doc: product}
+	}
+	inserted, err = seedMissing(ctx, db.Collection("products"), products, common.dryRun)
+	if err != nil {
+		return fmt.Errorf("seed products: %w", err)
+	}
+	fmt.Printf("%sinsert %d of %d demo products\n", prefix, inserted, len(products))
+
+	if *sample == 0 {
+		return nil
+	}
+	picked, err := helper.GetRandomDoc[models.Product](db, "products", *sample)
+	if err != nil {
+		return fmt.Errorf("sample products: %w", err)
+	}
+	for _, p := range picked {
+		fmt.Printf("  %-12s %-28s %8d\n", p.SKU, p.Name, p.EffectivePrice())
+	}
+	return nil
+}
+
+// demoProduct membuat produk demo ke-i. Hasilnya hanya bergantung pada rng, i dan kategori.
+func demoProduct(rng *rand.Rand, i int, categoryIDs []primitive.ObjectID, now time.Time) models.Product {
+	noun := demoNouns[rng.Intn(len(demoNouns))]
+	adjective := demoAdjectives[rng.Intn(len(demoAdjectives))]
+	original := int64(5+rng.Intn(196)) * 500
+	discount := original
+	if rng.Intn(3) == 0 {
+		discount = original * int64(70+rng.Intn(25)) / 100 / 500 * 500
+	}
+	stock := rng.Intn(101)
+	tags := []string{demoTags[rng.Intn(len(demoTags))], demoTags[rng.Intn(len(demoTags))]}
+
+	product := models.Product{
+		ID:            primitive.NewObjectID(),
+		SKU:           fmt.Sprintf("%s%04d", demoSKUPrefix, i+1),
+		Name:          noun + " " + adjective,
+		Description:   fmt.Sprintf("%s %s untuk data demo.", noun, strings.ToLower(adjective)),
+		DiscountPrice: discount,
+		OriginalPrice: original,
+		Stock:         &stock,
+		Tags:          models.NormalizeTags(tags),
+		CreatedAt:     primitive.NewDateTimeFromTime(now),
+		UpdatedAt:     primitive.NewDateTimeFromTime(now),
+	}
+	if len(categoryIDs) > 0 {
+		id := categoryIDs[rng.Intn(len(categoryIDs))]
+		product.CategoryID = &id
+	}
+	return product
+}
+
+// seedDoc adalah dokumen demo beserta filter yang menentukan apakah dokumen itu sudah ada
+type seedDoc struct {
+	filter bson.M
+	doc    interface{}
+}
+
+// seedMissing menyisipkan dokumen yang belum ada dan mengembalikan jumlahnya
+func seedMissing(ctx context.Context, collection *mongo.Collection, docs []seedDoc, dryRun bool) (int64, error) {
+	var inserted int64
+	for _, d := range docs {
+		if dryRun {
+			n, err := collection.CountDocuments(ctx, d.filter, options.Count().SetLimit(1))
+			if err != nil {
+				return inserted, err
+			}
+			if n == 0 {
+				inserted++
+			}
+			continue
+		}
+		res, err := collection.UpdateOne(ctx, d.filter, bson.M{"$setOnInsert": d.doc}, options.Update().SetUpsert(true))
+		if err != nil {
+			return inserted, err
+		}
+		if res.UpsertedCount > 0 {
+			inserted++
+		}
+	}
+	return inserted, nil
+}
+
+// runEnsureIndexes membuat semua index aplikasi
+func runEnsureIndexes(ctx context.Context, args []string) error {
+	fs, common := newFlagSet("ensure-indexes")
+	fs.Parse(args)
+
+	if common.dryRun {
+		for _, name := range models.IndexedCollections() {
+			fmt.Printf("[dry run] would ensure indexes on %s\n", name)
+		}
+		return nil
+	}
+	db, disconnect, err := connect(common.configFile)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	if err := models.EnsureIndexes(ctx, db); err != nil {
+		return err
+	}
+	fmt.Printf("indexes ensured on %d collections\n", len(models.IndexedCollections()))
+	return nil
+}
+
+// runPurge menghapus token blacklist yang JWT-nya sudah kedaluwarsa dan keranjang tamu
+// yang sudah lewat masa berlakunya tetapi belum dihapus TTL index
+func runPurge(ctx context.Context, args []string) error {
+	fs, common := newFlagSet("purge")
+	fs.Parse(args)
+
+	db, disconnect, err := connect(common.configFile)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	now := time.Now()
+	targets := []struct {
+		label      string
+		collection *mongo.Collection
+		filter     bson.M
+	}{
+		{"blacklisted tokens", db.Collection("blacklisted_tokens"), models.ExpiredBlacklistFilter(now.Add(-middleware.AccessTokenTTL))},
+		{"expired guest carts", db.Collection("carts"), models.ExpiredGuestCartsFilter(now)},
+	}
+	for _, t := range targets {
+		var n int64
+		if common.dryRun {
+			n, err = t.collection.CountDocuments(ctx, t.filter)
+		} else {
+			var res *mongo.DeleteResult
+			if res, err = t.collection.DeleteMany(ctx, t.filter); err == nil {
+				n = res.DeletedCount
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("purge %s: %w", t.label, err)
+		}
+		fmt.Printf("%sdelete %d %s\n", wouldPrefix(common.dryRun), n, t.label)
+	}
+	return nil
+}
+
+// runMigrate menjalankan migrasi data yang belum pernah dijalankan. Migrasi yang macet (diklaim
+// tetapi tidak pernah selesai, misalnya karena proses mati) dilaporkan dan menghentikan perintah,
+// kecuali diambil alih dengan -force atau karena sudah lebih lama dari -stale-after.
+func runMigrate(ctx context.Context, args []string) error {
+	fs, common := newFlagSet("migrate")
+	force := fs.Bool("force", false, "ambil alih migrasi yang macet tanpa menunggu -stale-after; pastikan tidak ada proses migrate lain yang berjalan")
+	staleAfter := fs.Duration("stale-after", 0, "ambil alih migrasi yang macet setelah durasi ini sejak started_at (0 = tidak pernah)")
+	fs.Parse(args)
+
+	db, disconnect, err := connect(common.configFile)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	migrations := db.Collection("migrations")
+
+	pending, err := models.PendingMigrations(ctx, migrations)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("no pending migrations")
+		return nil
+	}
+	for _, m := range pending {
+		takeOver := false
+		if m.Stuck() {
+			age := time.Since(*m.StartedAt).Round(time.Second)
+			fmt.Printf("stuck %s: started at %s (%s ago) and never finished\n", m.ID, m.StartedAt.Format(time.RFC3339), age)
+			takeOver = *force || (*staleAfter > 0 && age >= *staleAfter)
+			if !takeOver {
+				// Migrasi berikutnya bisa bergantung pada yang ini, jadi tidak dilewati
+				return fmt.Errorf("migration %s is stuck; if no other migrate process is running, rerun with -force", m.ID)
+			}
+		}
+		if common.dryRun {
+			n, err := m.Run(ctx, db, true)
+			if err != nil {
+				return fmt.Errorf("migration %s: %w", m.ID, err)
+			}
+			fmt.Printf("[dry run] would apply %s (%d documents): %s\n", m.ID, n, m.Description)
+			continue
+		}
+		var n int64
+		var applied bool
+		if takeOver {
+			n, applied, err = models.TakeOverMigration(ctx, db, migrations, m)
+		} else {
+			n, applied, err = models.ApplyMigration(ctx, db, migrations, m.Migration)
+		}
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+		if !applied {
+			fmt.Printf("skipped %s: claimed by another process\n", m.ID)
+			continue
+		}
+		fmt.Printf("applied %s (%d documents)\n", m.ID, n)
+	}
+	return nil
+}
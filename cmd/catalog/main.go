@@ -0,0 +1,189 @@
+// Command catalog mengimpor dan mengekspor katalog produk dalam format CSV atau JSONL,
+// memakai konfigurasi dan database yang sama dengan server.
+//
+//	catalog import [-config file] [-format csv|jsonl] [-dry-run] FILE
+//	catalog export [-config file] [-format csv|jsonl] [-o FILE]
+//
+// Impor keluar dengan kode 3 jika ada baris yang gagal, sehingga -dry-run bisa dipakai di CI.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/ChekoutGobiz/BackendChekout/config"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const usage = `Usage:
+  catalog import [-config file] [-format csv|jsonl] [-dry-run] FILE
+  catalog export [-config file] [-format csv|jsonl] [-o FILE]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(ctx, os.Args[2:])
+	case "export":
+		err = runExport(ctx, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var exit exitCode
+	if errors.As(err, &exit) {
+		os.Exit(int(exit))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// exitCode menghentikan program dengan kode tertentu tanpa pesan tambahan
+type exitCode int
+
+func (e exitCode) Error() string { return fmt.Sprintf("exit %d", int(e)) }
+
+// connect memuat konfigurasi dan membuka database
+func connect(configFile string) (*mongo.Database, func(), error) {
+	var args []string
+	if configFile != "" {
+		args = []string{"-config", configFile}
+	}
+	cfg, err := config.Load(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := config.ConnectDB(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client.Database(cfg.DBName), func() { client.Disconnect(context.Background()) }, nil
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path file konfigurasi YAML atau JSON")
+	format := fs.String("format", "", "csv atau jsonl (bawaan: dari ekstensi file)")
+	dryRun := fs.Bool("dry-run", false, "validasi saja tanpa menulis ke database")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitCode(2)
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			*format = models.CatalogCSV
+		case ".jsonl", ".ndjson":
+			*format = models.CatalogJSONL
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	db, disconnect, err := connect(*configFile)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	products := db.Collection("products")
+	if err := models.EnsureProductIndexes(ctx, products); err != nil {
+		return fmt.Errorf("index products: %w", err)
+	}
+
+	importer := models.CatalogImporter{
+		Products:   products,
+		Categories: db.Collection("categories"),
+		History:    db.Collection("price_history"),
+		DryRun:     *dryRun,
+		Progress: func(stats models.ImportStats) {
+			if info.Size() > 0 {
+				fmt.Fprintf(os.Stderr, "\r%d rows, %d%%", stats.Rows, stats.BytesRead*100/info.Size())
+			}
+		},
+	}
+	stats, err := importer.Import(ctx, bufio.NewReader(f), *format)
+	fmt.Fprintln(os.Stderr)
+	for _, rowErr := range stats.Errors {
+		fmt.Fprintf(os.Stderr, "row %d %s: %s\n", rowErr.Row, rowErr.SKU, rowErr.Error)
+	}
+	if stats.Failed > len(stats.Errors) {
+		fmt.Fprintf(os.Stderr, "... and %d more failed rows\n", stats.Failed-len(stats.Errors))
+	}
+	mode := ""
+	if *dryRun {
+		mode = " (dry run, nothing written)"
+	}
+	fmt.Printf("rows: %d, created: %d, updated: %d, unchanged: %d, failed: %d%s\n",
+		stats.Rows, stats.Created, stats.Updated, stats.Unchanged, stats.Failed, mode)
+	if err != nil {
+		return err
+	}
+	if stats.Failed > 0 {
+		return exitCode(3)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path file konfigurasi YAML atau JSON")
+	format := fs.String("format", models.CatalogCSV, "csv atau jsonl")
+	output := fs.String("o", "", "file tujuan (bawaan: stdout)")
+	fs.Parse(args)
+
+	db, disconnect, err := connect(*configFile)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	count, err := models.ExportCatalog(ctx, db.Collection("products"), db.Collection("categories"), w, *format)
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d products exported\n", count)
+	return nil
+}
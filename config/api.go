@@ -1,7 +1,5 @@
 package config
 
-import "os"
-
 var WAAPIQRLogin string = "https://api.wa.my.id/api/whatsauth/request"
 
 var WAAPIMessage string = "https://api.wa.my.id/api/v2/send/message/text"
@@ -24,4 +22,5 @@ var WAAPIToken string
 
 var APIGETPDLMS string = "https://pamongdesa.kemendagri.go.id/webservice/public/user/get-by-phone?number="
 
-var APITOKENPD string = os.Getenv("PDTOKEN")
+// APITOKENPD diisi dari Config.PDToken saat Load
+var APITOKENPD string
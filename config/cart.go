@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+)
+
+// CartConfig mengatur keranjang tamu dan penggabungannya saat login
+type CartConfig struct {
+	// GuestTTL adalah masa berlaku keranjang tamu sejak terakhir diubah
+	GuestTTL Duration `json:"guest_ttl" yaml:"guest_ttl"`
+	// MergeStrategy menentukan jumlah item yang ada di keranjang tamu dan keranjang pengguna:
+	// sum (dijumlahkan), max (ambil yang terbesar) atau prefer_guest (pakai jumlah di keranjang tamu)
+	MergeStrategy string `json:"merge_strategy" yaml:"merge_strategy"`
+	// MaxQuantity adalah jumlah maksimal satu produk dalam keranjang
+	MaxQuantity int `json:"max_quantity" yaml:"max_quantity"`
+}
+
+func defaultCart() CartConfig {
+	return CartConfig{
+		GuestTTL:      Duration(30 * 24 * time.Hour),
+		MergeStrategy: models.CartMergeSum,
+		MaxQuantity:   99,
+	}
+}
+
+func (c CartConfig) validate() []string {
+	var errs []string
+	if c.GuestTTL <= 0 {
+		errs = append(errs, "cart.guest_ttl harus lebih dari 0")
+	}
+	if c.MaxQuantity <= 0 {
+		errs = append(errs, "cart.max_quantity harus lebih dari 0")
+	}
+	switch c.MergeStrategy {
+	case models.CartMergeSum, models.CartMergeMax, models.CartMergePreferGuest:
+	default:
+		errs = append(errs, fmt.Sprintf("cart.merge_strategy harus sum, max atau prefer_guest, didapat %q", c.MergeStrategy))
+	}
+	return errs
+}
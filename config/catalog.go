@@ -0,0 +1,19 @@
+package config
+
+// CatalogConfig mengatur impor katalog produk
+type CatalogConfig struct {
+	// ImportMaxBytes adalah ukuran maksimal file impor CSV atau JSONL
+	ImportMaxBytes int `json:"import_max_bytes" yaml:"import_max_bytes"`
+}
+
+func defaultCatalog() CatalogConfig {
+	return CatalogConfig{ImportMaxBytes: 100 << 20}
+}
+
+func (c CatalogConfig) validate() []string {
+	var errs []string
+	if c.ImportMaxBytes <= 0 {
+		errs = append(errs, "catalog.import_max_bytes harus lebih dari 0")
+	}
+	return errs
+}
@@ -1,26 +1,388 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
-// Konfigurasi Fiber App
-var GoBiz = fiber.Config{
-	Prefork:       true,
-	CaseSensitive: true,
-	StrictRouting: true,
-	ServerHeader:  "GoBiz",
-	AppName:       "Gibizyuhu",
-}
-
-// Konfigurasi CORS (Fiber middleware cors.New)
-var Cors = cors.New(cors.Config{
-    AllowOrigins: "https://satsetin.github.io", // Izinkan origin frontend
-    AllowMethods: "GET,POST,PUT,DELETE",        // Izinkan metode HTTP
-    AllowHeaders: "Content-Type,Authorization",  // Izinkan header tertentu
-    AllowCredentials: true,  
-})
-
-// IPPort bisa disesuaikan dengan environment atau hardcoded
-var IPPort = ":8080"
+// Config adalah seluruh konfigurasi aplikasi.
+// Urutan prioritas (dari yang paling kuat): flag, environment variable, file konfigurasi, nilai default.
+type Config struct {
+	AppName string `json:"app_name" yaml:"app_name"`
+	// LogLevel: debug, info, warn atau error
+	LogLevel string `json:"log_level" yaml:"log_level"`
+	// Address dan Network adalah alamat listen, default dari helper.GetAddress (PORT dan IP)
+	Address string `json:"address" yaml:"address"`
+	Network string `json:"network" yaml:"network"`
+	Prefork bool   `json:"prefork" yaml:"prefork"`
+	// ShutdownTimeout adalah batas waktu menyelesaikan request yang sedang berjalan saat berhenti
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	// ShutdownDelay adalah jeda antara readiness gagal dan listener ditutup,
+	// memberi waktu load balancer berhenti mengirim request baru
+	ShutdownDelay Duration `json:"shutdown_delay" yaml:"shutdown_delay"`
+
+	MongoURI string `json:"mongo_uri" yaml:"mongo_uri"`
+	DBName   string `json:"db_name" yaml:"db_name"`
+
+	// JWTSecret adalah secret HS256 lama, hanya untuk kompatibilitas selama migrasi ke JWT.Keys
+	JWTSecret string    `json:"jwt_secret" yaml:"jwt_secret"`
+	JWT       JWTConfig `json:"jwt" yaml:"jwt"`
+	PDToken   string    `json:"pd_token" yaml:"pd_token"`
+
+	CORS      CORSConfig      `json:"cors" yaml:"cors"`
+	Proxy     ProxyConfig     `json:"proxy" yaml:"proxy"`
+	Tracing   TracingConfig   `json:"tracing" yaml:"tracing"`
+	RateLimit RateLimitConfig `json:"rate_limit" yaml:"rate_limit"`
+	Mail      MailConfig      `json:"mail" yaml:"mail"`
+	Cart      CartConfig      `json:"cart" yaml:"cart"`
+	Wishlist  WishlistConfig  `json:"wishlist" yaml:"wishlist"`
+	Review    ReviewConfig    `json:"review" yaml:"review"`
+	Pricing   PricingConfig   `json:"pricing" yaml:"pricing"`
+	Catalog   CatalogConfig   `json:"catalog" yaml:"catalog"`
+	Media     MediaConfig     `json:"media" yaml:"media"`
+}
+
+// Default mengembalikan konfigurasi bawaan sebelum file, env dan flag diterapkan
+func Default() *Config {
+	address, network := helper.GetAddress()
+	return &Config{
+		AppName:         "Gibizyuhu",
+		LogLevel:        "info",
+		Address:         address,
+		Network:         network,
+		Prefork:         true,
+		ShutdownTimeout: Duration(15 * time.Second),
+		DBName:          "jajankuy",
+		JWT:             defaultJWT(),
+		CORS:            DefaultCORS(),
+		Tracing:         defaultTracing(),
+		RateLimit:       defaultRateLimit(),
+		Cart:            defaultCart(),
+		Wishlist:        defaultWishlist(),
+		Pricing:         defaultPricing(),
+		Catalog:         defaultCatalog(),
+		Media:           defaultMedia(),
+		Review:          defaultReview(),
+		Mail: MailConfig{
+			Transport:      MailTransportSMTP,
+			SMTPPort:       587,
+			From:           "GoBiz <no-reply@gobiz.local>",
+			LinkBaseURL:    "https://satsetin.github.io",
+			VerifyTokenTTL: Duration(24 * time.Hour),
+			ResetTokenTTL:  Duration(time.Hour),
+		},
+	}
+}
+
+// Load membaca konfigurasi dari file (opsional), environment variable dan flag (args tanpa nama program).
+// File .env dimuat jika ada, tetapi tidak wajib.
+func Load(args []string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	cfg := Default()
+
+	fs := flag.NewFlagSet("gobiz", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path file konfigurasi YAML atau JSON")
+	address := fs.String("addr", "", "alamat listen, contoh :8080")
+	prefork := fs.Bool("prefork", cfg.Prefork, "jalankan Fiber dengan Prefork")
+	mongoURI := fs.String("mongo-uri", "", "connection string MongoDB")
+	dbName := fs.String("db-name", "", "nama database MongoDB")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []string
+	if err := cfg.loadEnv(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Address = *address
+		case "prefork":
+			cfg.Prefork = *prefork
+		case "mongo-uri":
+			cfg.MongoURI = *mongoURI
+		case "db-name":
+			cfg.DBName = *dbName
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+
+	APITOKENPD = cfg.PDToken
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("baca file konfigurasi: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".json":
+		err = json.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("format file konfigurasi tidak dikenal: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parse file konfigurasi %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	if os.Getenv("PORT") != "" {
+		c.Address, c.Network = helper.GetAddress()
+	}
+	setString(&c.AppName, "APP_NAME")
+	setString(&c.LogLevel, "LOG_LEVEL")
+	// MONGOSTRING tetap didukung untuk kompatibilitas
+	setString(&c.MongoURI, "MONGOSTRING")
+	setString(&c.MongoURI, "MONGODB_URI")
+	setString(&c.DBName, "DB_NAME")
+	setString(&c.JWTSecret, "JWT_SECRET")
+	setString(&c.PDToken, "PDTOKEN")
+	c.JWT.loadEnv()
+	setList(&c.CORS.AllowOrigins, "CORS_ALLOW_ORIGINS")
+	setString(&c.Proxy.Header, "PROXY_HEADER")
+	setList(&c.Proxy.TrustedProxies, "TRUSTED_PROXIES")
+	// Nama variabel mengikuti konvensi OpenTelemetry
+	setString(&c.Tracing.Exporter, "OTEL_TRACES_EXPORTER")
+	setString(&c.Tracing.Endpoint, "OTEL_EXPORTER_OTLP_ENDPOINT")
+	setString(&c.RateLimit.Store, "RATE_LIMIT_STORE")
+	setString(&c.Mail.Transport, "MAIL_TRANSPORT")
+	setString(&c.Mail.SMTPHost, "SMTP_HOST")
+	setString(&c.Mail.SMTPUsername, "SMTP_USERNAME")
+	setString(&c.Mail.SMTPPassword, "SMTP_PASSWORD")
+	setString(&c.Mail.From, "MAIL_FROM")
+	setString(&c.Mail.LinkBaseURL, "MAIL_LINK_BASE_URL")
+	setString(&c.Cart.MergeStrategy, "CART_MERGE_STRATEGY")
+	setString(&c.Media.Store, "MEDIA_STORE")
+	setString(&c.Media.LocalDir, "MEDIA_LOCAL_DIR")
+	setString(&c.Media.PublicURL, "MEDIA_PUBLIC_URL")
+	setString(&c.Media.S3Endpoint, "S3_ENDPOINT")
+	setString(&c.Media.S3Region, "S3_REGION")
+	setString(&c.Media.S3Bucket, "S3_BUCKET")
+	setString(&c.Media.S3AccessKeyID, "S3_ACCESS_KEY_ID")
+	setString(&c.Media.S3SecretAccessKey, "S3_SECRET_ACCESS_KEY")
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("SMTP_PORT harus angka, didapat %q", v)
+		}
+		c.Mail.SMTPPort = port
+	}
+
+	if v := os.Getenv("PREFORK"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("PREFORK harus boolean, didapat %q", v)
+		}
+		c.Prefork = b
+	}
+	if v := os.Getenv("REVIEW_REQUIRE_VERIFIED_PURCHASE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("REVIEW_REQUIRE_VERIFIED_PURCHASE harus boolean, didapat %q", v)
+		}
+		c.Review.RequireVerifiedPurchase = b
+	}
+	if err := setDuration(&c.ShutdownTimeout, "SHUTDOWN_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.ShutdownDelay, "SHUTDOWN_DELAY"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Cart.GuestTTL, "CART_GUEST_TTL"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Wishlist.PriceDropInterval, "WISHLIST_PRICE_DROP_INTERVAL"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Pricing.SaleSchedulerInterval, "SALE_SCHEDULER_INTERVAL"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func setString(target *string, key string) {
+	if v := os.Getenv(key); v != "" {
+		*target = v
+	}
+}
+
+func setDuration(target *Duration, key string) error {
+	if v := os.Getenv(key); v != "" {
+		if err := target.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func setList(target *[]string, key string) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	*target = nil
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*target = append(*target, item)
+		}
+	}
+}
+
+// Validate memeriksa konfigurasi wajib dan mengembalikan semua kesalahan sekaligus
+func (c *Config) Validate() error {
+	var errs []string
+	if _, err := c.SlogLevel(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if c.Address == "" {
+		errs = append(errs, "address wajib diisi")
+	}
+	if c.Network != "tcp" && c.Network != "tcp4" && c.Network != "tcp6" {
+		errs = append(errs, fmt.Sprintf("network harus tcp, tcp4 atau tcp6, didapat %q", c.Network))
+	}
+	if c.MongoURI == "" {
+		errs = append(errs, "mongo_uri wajib diisi (MONGODB_URI)")
+	} else if !strings.HasPrefix(c.MongoURI, "mongodb://") && !strings.HasPrefix(c.MongoURI, "mongodb+srv://") {
+		errs = append(errs, "mongo_uri harus diawali mongodb:// atau mongodb+srv://")
+	}
+	if c.DBName == "" {
+		errs = append(errs, "db_name wajib diisi")
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, "shutdown_timeout harus lebih dari 0")
+	}
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, "shutdown_delay tidak boleh negatif")
+	}
+	errs = append(errs, c.JWT.validate(c.JWTSecret)...)
+	errs = append(errs, c.CORS.validate()...)
+	errs = append(errs, c.Proxy.validate()...)
+	errs = append(errs, c.Tracing.validate()...)
+	errs = append(errs, c.RateLimit.validate()...)
+	errs = append(errs, c.Cart.validate()...)
+	errs = append(errs, c.Wishlist.validate()...)
+	errs = append(errs, c.Pricing.validate()...)
+	errs = append(errs, c.Catalog.validate()...)
+	errs = append(errs, c.Media.validate()...)
+	errs = append(errs, c.Mail.validate()...)
+	if len(errs) > 0 {
+		return fmt.Errorf("konfigurasi tidak valid: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// SlogLevel mengubah LogLevel menjadi slog.Level
+func (c *Config) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return level, fmt.Errorf("log_level tidak valid %q", c.LogLevel)
+	}
+	return level, nil
+}
+
+// Redacted mengembalikan salinan konfigurasi dengan semua secret disamarkan
+func (c *Config) Redacted() Config {
+	r := *c
+	r.MongoURI = redactURI(c.MongoURI)
+	r.JWTSecret = redact(c.JWTSecret)
+	r.PDToken = redact(c.PDToken)
+	r.JWT.Keys = make([]JWTKeyConfig, len(c.JWT.Keys))
+	for i, key := range c.JWT.Keys {
+		key.PrivateKey = redact(key.PrivateKey)
+		r.JWT.Keys[i] = key
+	}
+	r.Mail.SMTPPassword = redact(c.Mail.SMTPPassword)
+	r.Media.S3SecretAccessKey = redact(c.Media.S3SecretAccessKey)
+	return r
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "[REDACTED]"
+}
+
+// redactURI hanya menyamarkan password pada connection string
+func redactURI(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redact(raw)
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	}
+	return u.String()
+}
+
+// Fiber mengembalikan konfigurasi Fiber App
+func (c *Config) Fiber() fiber.Config {
+	return fiber.Config{
+		Prefork:       c.Prefork,
+		Network:       c.Network,
+		CaseSensitive: true,
+		StrictRouting: true,
+		ServerHeader:  "GoBiz",
+		AppName:       c.AppName,
+		// c.IP() hanya membaca Proxy.Header dari request yang datang lewat Proxy.TrustedProxies
+		ProxyHeader:             c.Proxy.Header,
+		EnableTrustedProxyCheck: len(c.Proxy.TrustedProxies) > 0,
+		TrustedProxies:          c.Proxy.TrustedProxies,
+		EnableIPValidation:      true,
+		// Body tidak di-buffer fasthttp dan multipart tidak di-parse lebih dulu; middleware.LimitBody
+		// menerapkan BodyLimit untuk semua route kecuali impor katalog, yang menyalin body
+		// langsung ke file sementara dengan batas Catalog.ImportMaxBytes
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+		BodyLimit:                    c.BodyLimit(),
+	}
+}
+
+// BodyLimit adalah ukuran body maksimal untuk route biasa: upload gambar terbesar ditambah ruang
+// untuk field multipart lain
+func (c *Config) BodyLimit() int {
+	return maxInt(fiber.DefaultBodyLimit, c.Media.MaxUploadBytes+64<<10)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,50 +1,84 @@
 package config
 
 import (
-	"net/http"
+	"fmt"
+	"strings"
 )
 
-// Daftar origins yang diizinkan
-var Origins = []string{
-	"https://www.bukupedia.co.id",
-	"https://naskah.bukupedia.co.id",
-	"https://bukupedia.co.id",
-	"http://127.0.0.1:5502", // Pastikan ini ada
+// CORSPolicy adalah aturan CORS untuk sekelompok route
+type CORSPolicy struct {
+	// AllowOrigins mendukung origin persis ("https://satsetin.github.io"),
+	// wildcard subdomain ("https://*.gobiz.id") dan "*" untuk semua origin.
+	// "*" dan wildcard seluruh TLD ("https://*.com") ditolak jika AllowCredentials aktif.
+	AllowOrigins     []string `json:"allow_origins" yaml:"allow_origins"`
+	AllowMethods     []string `json:"allow_methods" yaml:"allow_methods"`
+	AllowHeaders     []string `json:"allow_headers" yaml:"allow_headers"`
+	ExposeHeaders    []string `json:"expose_headers" yaml:"expose_headers"`
+	AllowCredentials bool     `json:"allow_credentials" yaml:"allow_credentials"`
+	// MaxAge adalah lama cache preflight dalam detik
+	MaxAge int `json:"max_age" yaml:"max_age"`
 }
 
-// Fungsi untuk memeriksa apakah origin diizinkan
-func isAllowedOrigin(origin string) bool {
-	for _, o := range Origins {
-		if o == origin {
-			return true
-		}
-	}
-	return false
+// CORSConfig adalah policy default ditambah override per prefix route.
+// Override dengan prefix terpanjang yang cocok dipakai menggantikan policy default.
+type CORSConfig struct {
+	CORSPolicy `yaml:",inline"`
+	Routes     map[string]CORSPolicy `json:"routes" yaml:"routes"`
 }
 
-// Fungsi untuk mengatur header CORS
-func SetAccessControlHeaders(w http.ResponseWriter, r *http.Request) bool {
-	origin := r.Header.Get("Origin")
+// DefaultCORS mengembalikan policy CORS bawaan untuk frontend
+func DefaultCORS() CORSConfig {
+	return CORSConfig{
+		CORSPolicy: CORSPolicy{
+			AllowOrigins:     []string{"https://satsetin.github.io"},
+			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowHeaders:     []string{"Content-Type", "Authorization", "X-Cart-Token", "If-Match"},
+			ExposeHeaders:    []string{"X-Cart-Token", "ETag"},
+			AllowCredentials: true,
+			MaxAge:           3600,
+		},
+	}
+}
 
-	// Cek apakah origin diizinkan
-	if isAllowedOrigin(origin) {
-		// Untuk permintaan preflight (OPTIONS)
-		if r.Method == http.MethodOptions {
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Login,Authorization") // pastikan 'Authorization' ada jika digunakan
-			w.Header().Set("Access-Control-Allow-Methods", "POST,GET,DELETE,PUT,OPTIONS")
-			w.Header().Set("Access-Control-Allow-Origin", origin) // Allow origin yang sesuai
-			w.Header().Set("Access-Control-Max-Age", "3600") // Waktu cache preflight request
-			w.WriteHeader(http.StatusNoContent) // Tidak ada konten untuk preflight request
-			return true
+// PolicyFor mengembalikan policy yang berlaku untuk path
+func (c CORSConfig) PolicyFor(path string) CORSPolicy {
+	policy, matched := c.CORSPolicy, ""
+	for prefix, p := range c.Routes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			policy, matched = p, prefix
 		}
+	}
+	return policy
+}
 
-		// Untuk permintaan utama (POST, GET, PUT, dll)
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Allow-Origin", origin) // Allow origin yang sesuai
-		return false
+func (c CORSConfig) validate() []string {
+	var errs []string
+	policies := map[string]CORSPolicy{"cors": c.CORSPolicy}
+	for prefix, p := range c.Routes {
+		policies["cors.routes."+prefix] = p
 	}
+	for name, p := range policies {
+		for _, origin := range p.AllowOrigins {
+			if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+				errs = append(errs, fmt.Sprintf("%s: origin %q harus diawali http:// atau https://", name, origin))
+			}
+			if p.AllowCredentials && openOrigin(origin) {
+				errs = append(errs, fmt.Sprintf("%s: origin %q tidak boleh dipakai dengan allow_credentials", name, origin))
+			}
+		}
+		if p.MaxAge < 0 {
+			errs = append(errs, fmt.Sprintf("%s: max_age tidak boleh negatif", name))
+		}
+	}
+	return errs
+}
 
-	// Jika origin tidak diizinkan, jangan lanjutkan permintaan
-	return false
+// openOrigin melaporkan apakah pola origin cocok dengan situs siapa saja: "*" atau wildcard
+// yang hanya menyisakan TLD seperti "https://*.com"
+func openOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	_, host, ok := strings.Cut(origin, "://*.")
+	return ok && !strings.Contains(host, ".")
 }
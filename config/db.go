@@ -2,36 +2,66 @@ package config
 
 import (
 	"context"
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/ChekoutGobiz/BackendChekout/metrics"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 var DB *mongo.Client
 
 // Koneksi ke MongoDB
-func ConnectDB() {
-	mongoURI := os.Getenv("MONGOSTRING")
-	if mongoURI == "" {
-		log.Fatal("MongoDB connection string (MONGOSTRING) tidak ditemukan di environment.")
-	}
-
+func ConnectDB(cfg *Config) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	clientOptions := options.Client().
+		ApplyURI(cfg.MongoURI).
+		SetMonitor(chainCommandMonitors(metrics.CommandMonitor(), otelmongo.NewMonitor())).
+		SetPoolMonitor(metrics.PoolMonitor())
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		log.Fatalf("Gagal koneksi ke MongoDB: %v", err)
+		return nil, fmt.Errorf("gagal koneksi ke MongoDB: %w", err)
 	}
 
 	// Ping database untuk memastikan koneksi
 	if err := client.Ping(ctx, nil); err != nil {
-		log.Fatalf("Gagal ping ke MongoDB: %v", err)
+		return nil, fmt.Errorf("gagal ping ke MongoDB: %w", err)
 	}
 
-	log.Println("Koneksi MongoDB berhasil.")
+	slog.Info("Koneksi MongoDB berhasil")
 	DB = client
+	return client, nil
+}
+
+// chainCommandMonitors menggabungkan beberapa CommandMonitor karena driver hanya menerima satu
+func chainCommandMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
 }
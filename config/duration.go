@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration adalah time.Duration yang ditulis sebagai string ("15s", "5m") di file konfigurasi dan env
+type Duration time.Duration
+
+// Std mengembalikan nilai sebagai time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("durasi tidak valid %q: %w", text, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
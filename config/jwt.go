@@ -0,0 +1,154 @@
+package config
+
+import (
+	"crypto"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+)
+
+// JWTConfig mengatur penandatanganan dan verifikasi JWT
+type JWTConfig struct {
+	Issuer   string `json:"issuer" yaml:"issuer"`
+	Audience string `json:"audience" yaml:"audience"`
+	// ActiveKey adalah kid yang dipakai menandatangani token baru
+	ActiveKey string `json:"active_key" yaml:"active_key"`
+	// Keys berisi kunci aktif dan kunci lama yang masih diterima untuk verifikasi.
+	// Saat rotasi, tambahkan kunci baru, pindahkan ActiveKey, lalu hapus private key kunci lama
+	// dan buang kunci itu setelah token terakhirnya kedaluwarsa.
+	Keys []JWTKeyConfig `json:"keys" yaml:"keys"`
+}
+
+// JWTKeyConfig adalah satu kunci RS256 atau EdDSA dalam format PEM (inline atau file)
+type JWTKeyConfig struct {
+	ID        string `json:"id" yaml:"id"`
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	// Private key boleh kosong untuk kunci yang hanya dipakai verifikasi
+	PrivateKey     string `json:"private_key" yaml:"private_key"`
+	PrivateKeyFile string `json:"private_key_file" yaml:"private_key_file"`
+	PublicKey      string `json:"public_key" yaml:"public_key"`
+	PublicKeyFile  string `json:"public_key_file" yaml:"public_key_file"`
+}
+
+func defaultJWT() JWTConfig {
+	return JWTConfig{
+		Issuer:   "gobiz",
+		Audience: "gobiz-api",
+	}
+}
+
+func (j *JWTConfig) loadEnv() {
+	setString(&j.Issuer, "JWT_ISSUER")
+	setString(&j.Audience, "JWT_AUDIENCE")
+	// Satu kunci aktif lewat environment; kunci tambahan untuk rotasi diatur di file konfigurasi
+	key := JWTKeyConfig{ID: os.Getenv("JWT_KEY_ID"), Algorithm: os.Getenv("JWT_ALGORITHM")}
+	setString(&key.PrivateKey, "JWT_PRIVATE_KEY")
+	setString(&key.PrivateKeyFile, "JWT_PRIVATE_KEY_FILE")
+	if key.ID != "" && (key.PrivateKey != "" || key.PrivateKeyFile != "") {
+		j.Keys = append(j.Keys, key)
+		j.ActiveKey = key.ID
+	}
+}
+
+func (j JWTConfig) validate(legacySecret string) []string {
+	var errs []string
+	if j.Issuer == "" || j.Audience == "" {
+		errs = append(errs, "jwt.issuer dan jwt.audience wajib diisi")
+	}
+	if len(j.Keys) == 0 {
+		if legacySecret == "" {
+			errs = append(errs, "jwt.keys atau jwt_secret wajib diisi (JWT_PRIVATE_KEY_FILE atau JWT_SECRET)")
+		}
+		return errs
+	}
+	seen := map[string]bool{}
+	activeFound := false
+	for i, key := range j.Keys {
+		if key.ID == "" {
+			errs = append(errs, fmt.Sprintf("jwt.keys[%d].id wajib diisi", i))
+		} else if seen[key.ID] {
+			errs = append(errs, fmt.Sprintf("jwt.keys: id %q terdaftar dua kali", key.ID))
+		}
+		seen[key.ID] = true
+		if key.Algorithm != "" && key.Algorithm != helper.JWTAlgRS256 && key.Algorithm != helper.JWTAlgEdDSA {
+			errs = append(errs, fmt.Sprintf("jwt.keys[%d].algorithm harus RS256 atau EdDSA, didapat %q", i, key.Algorithm))
+		}
+		hasPrivate := key.PrivateKey != "" || key.PrivateKeyFile != ""
+		if !hasPrivate && key.PublicKey == "" && key.PublicKeyFile == "" {
+			errs = append(errs, fmt.Sprintf("jwt.keys[%d]: private_key atau public_key wajib diisi", i))
+		}
+		if key.ID == j.ActiveKey {
+			activeFound = true
+			if !hasPrivate {
+				errs = append(errs, fmt.Sprintf("jwt.active_key %q tidak punya private key", j.ActiveKey))
+			}
+		}
+	}
+	if !activeFound {
+		errs = append(errs, fmt.Sprintf("jwt.active_key %q tidak ada di jwt.keys", j.ActiveKey))
+	}
+	return errs
+}
+
+// NewKeySet memuat kunci JWT dari konfigurasi. JWTSecret (HS256) tetap diterima untuk token lama;
+// jika belum ada kunci asimetris, secret itu juga dipakai menandatangani.
+func NewKeySet(cfg *Config) (*helper.KeySet, error) {
+	ks := helper.NewKeySet(cfg.JWT.Issuer, cfg.JWT.Audience)
+	if cfg.JWTSecret != "" {
+		ks.SetLegacySecret(cfg.JWTSecret)
+	}
+	for _, key := range cfg.JWT.Keys {
+		private, public, err := key.load()
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", key.ID, err)
+		}
+		if err := ks.AddKey(key.ID, key.Algorithm, private, public); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.JWT.Keys) == 0 {
+		slog.Warn("jwt.keys kosong, token ditandatangani dengan HS256 JWT_SECRET dan JWKS kosong")
+		return ks, nil
+	}
+	if err := ks.SetActive(cfg.JWT.ActiveKey); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (k JWTKeyConfig) load() (crypto.Signer, crypto.PublicKey, error) {
+	var private crypto.Signer
+	var public crypto.PublicKey
+
+	privatePEM, err := pemValue(k.PrivateKey, k.PrivateKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if privatePEM != nil {
+		if private, err = helper.ParsePrivateKeyPEM(privatePEM); err != nil {
+			return nil, nil, err
+		}
+	}
+	publicPEM, err := pemValue(k.PublicKey, k.PublicKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if publicPEM != nil {
+		if public, err = helper.ParsePublicKeyPEM(publicPEM); err != nil {
+			return nil, nil, err
+		}
+	}
+	return private, public, nil
+}
+
+func pemValue(inline, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+	return os.ReadFile(file)
+}
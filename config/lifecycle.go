@@ -0,0 +1,52 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+type shutdownHook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+var (
+	hooksMu       sync.Mutex
+	shutdownHooks []shutdownHook
+
+	shuttingDown atomic.Bool
+)
+
+// BeginShutdown menandai aplikasi sedang berhenti sehingga readiness mulai gagal
+func BeginShutdown() {
+	shuttingDown.Store(true)
+}
+
+// ShuttingDown melaporkan apakah graceful shutdown sudah dimulai
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// OnShutdown mendaftarkan fungsi yang dijalankan saat graceful shutdown.
+// Hook dijalankan dengan urutan terbalik dari pendaftaran, seperti defer.
+func OnShutdown(name string, fn func(context.Context) error) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	shutdownHooks = append(shutdownHooks, shutdownHook{name: name, fn: fn})
+}
+
+// Shutdown menjalankan semua hook yang terdaftar sampai ctx habis
+func Shutdown(ctx context.Context) {
+	hooksMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	hooksMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].fn(ctx); err != nil {
+			slog.ErrorContext(ctx, "Shutdown hook gagal", "hook", hooks[i].name, "error", err)
+		}
+	}
+}
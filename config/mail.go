@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+)
+
+// Transport email yang didukung
+const (
+	MailTransportSMTP = "smtp"
+	// MailTransportMemory hanya menyimpan email di memori proses, untuk development dan test
+	MailTransportMemory = "memory"
+)
+
+// MailConfig mengatur pengiriman email dan link yang dikirim ke pengguna
+type MailConfig struct {
+	// Transport: smtp (bawaan, SMTPHost wajib diisi) atau memory (email tidak pernah terkirim)
+	Transport    string `json:"transport" yaml:"transport"`
+	SMTPHost     string `json:"smtp_host" yaml:"smtp_host"`
+	SMTPPort     int    `json:"smtp_port" yaml:"smtp_port"`
+	SMTPUsername string `json:"smtp_username" yaml:"smtp_username"`
+	SMTPPassword string `json:"smtp_password" yaml:"smtp_password"`
+	From         string `json:"from" yaml:"from"`
+	// LinkBaseURL adalah alamat frontend untuk link verifikasi dan reset password
+	LinkBaseURL string `json:"link_base_url" yaml:"link_base_url"`
+	// Masa berlaku token di link email
+	VerifyTokenTTL Duration `json:"verify_token_ttl" yaml:"verify_token_ttl"`
+	ResetTokenTTL  Duration `json:"reset_token_ttl" yaml:"reset_token_ttl"`
+}
+
+func (m MailConfig) validate() []string {
+	var errs []string
+	switch m.Transport {
+	case MailTransportSMTP:
+		if m.SMTPHost == "" {
+			errs = append(errs, "mail.smtp_host wajib diisi (SMTP_HOST), atau pakai mail.transport memory untuk development")
+		}
+	case MailTransportMemory:
+	default:
+		errs = append(errs, fmt.Sprintf("mail.transport harus smtp atau memory, didapat %q", m.Transport))
+	}
+	if m.LinkBaseURL == "" {
+		errs = append(errs, "mail.link_base_url wajib diisi")
+	}
+	if m.VerifyTokenTTL <= 0 || m.ResetTokenTTL <= 0 {
+		errs = append(errs, "mail.verify_token_ttl dan mail.reset_token_ttl harus lebih dari 0")
+	}
+	return errs
+}
+
+// NewMailer membuat Mailer sesuai konfigurasi
+func NewMailer(cfg *Config) helper.Mailer {
+	if cfg.Mail.Transport == MailTransportMemory {
+		slog.Warn("MAIL_TRANSPORT=memory, email tidak dikirim dan hanya disimpan di memori")
+		return &helper.MemoryMailer{}
+	}
+	return &helper.SMTPMailer{
+		Host:     cfg.Mail.SMTPHost,
+		Port:     cfg.Mail.SMTPPort,
+		Username: cfg.Mail.SMTPUsername,
+		Password: cfg.Mail.SMTPPassword,
+		From:     cfg.Mail.From,
+	}
+}
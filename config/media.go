@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+)
+
+// Penyimpanan file upload
+const (
+	MediaStoreLocal = "local"
+	MediaStoreS3    = "s3"
+)
+
+// MediaConfig mengatur upload gambar produk dan tempat penyimpanannya
+type MediaConfig struct {
+	// Store adalah local (filesystem) atau s3 (layanan kompatibel S3)
+	Store string `json:"store" yaml:"store"`
+	// LocalDir adalah folder penyimpanan untuk store local
+	LocalDir string `json:"local_dir" yaml:"local_dir"`
+	// PublicURL adalah prefix URL file. Untuk store local, path seperti "/media" dilayani oleh aplikasi ini.
+	PublicURL string `json:"public_url" yaml:"public_url"`
+	// MaxUploadBytes adalah ukuran maksimal satu file upload
+	MaxUploadBytes int `json:"max_upload_bytes" yaml:"max_upload_bytes"`
+
+	S3Endpoint        string `json:"s3_endpoint" yaml:"s3_endpoint"`
+	S3Region          string `json:"s3_region" yaml:"s3_region"`
+	S3Bucket          string `json:"s3_bucket" yaml:"s3_bucket"`
+	S3AccessKeyID     string `json:"s3_access_key_id" yaml:"s3_access_key_id"`
+	S3SecretAccessKey string `json:"s3_secret_access_key" yaml:"s3_secret_access_key"`
+}
+
+func defaultMedia() MediaConfig {
+	return MediaConfig{
+		Store:          MediaStoreLocal,
+		LocalDir:       "uploads",
+		PublicURL:      "/media",
+		MaxUploadBytes: 10 << 20,
+		S3Region:       "us-east-1",
+	}
+}
+
+func (c MediaConfig) validate() []string {
+	var errs []string
+	if c.MaxUploadBytes <= 0 {
+		errs = append(errs, "media.max_upload_bytes harus lebih dari 0")
+	}
+	switch c.Store {
+	case MediaStoreLocal:
+		if c.LocalDir == "" {
+			errs = append(errs, "media.local_dir wajib diisi untuk store local")
+		}
+		if c.PublicURL == "" {
+			errs = append(errs, "media.public_url wajib diisi untuk store local")
+		}
+	case MediaStoreS3:
+		if c.S3Endpoint == "" || c.S3Bucket == "" || c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			errs = append(errs, "media.s3_endpoint, s3_bucket, s3_access_key_id dan s3_secret_access_key wajib diisi untuk store s3")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("media.store harus local atau s3, didapat %q", c.Store))
+	}
+	return errs
+}
+
+// ServesLocalFiles melaporkan apakah aplikasi ini harus melayani file upload sendiri
+// (store local dengan PublicURL berupa path)
+func (c MediaConfig) ServesLocalFiles() bool {
+	return c.Store == MediaStoreLocal && strings.HasPrefix(c.PublicURL, "/")
+}
+
+// NewBlobStore membuat BlobStore sesuai konfigurasi
+func NewBlobStore(cfg *Config) helper.BlobStore {
+	m := cfg.Media
+	if m.Store == MediaStoreS3 {
+		return &helper.S3BlobStore{
+			Endpoint:        m.S3Endpoint,
+			Region:          m.S3Region,
+			Bucket:          m.S3Bucket,
+			AccessKeyID:     m.S3AccessKeyID,
+			SecretAccessKey: m.S3SecretAccessKey,
+			PublicURL:       m.PublicURL,
+			Client:          &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	if m.ServesLocalFiles() {
+		slog.Warn("Media disimpan di filesystem lokal; gunakan store s3 jika ada lebih dari satu server", "dir", m.LocalDir)
+	}
+	return &helper.LocalBlobStore{Dir: m.LocalDir, BaseURL: m.PublicURL}
+}
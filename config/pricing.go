@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// PricingConfig mengatur penjadwal diskon produk
+type PricingConfig struct {
+	// SaleSchedulerInterval adalah jarak antar pemeriksaan diskon terjadwal. 0 menonaktifkan penjadwal;
+	// harga keranjang tetap mengikuti jendela diskon, tetapi harga tersimpan tidak diperbarui.
+	SaleSchedulerInterval Duration `json:"sale_scheduler_interval" yaml:"sale_scheduler_interval"`
+}
+
+func defaultPricing() PricingConfig {
+	return PricingConfig{SaleSchedulerInterval: Duration(time.Minute)}
+}
+
+func (c PricingConfig) validate() []string {
+	var errs []string
+	if c.SaleSchedulerInterval < 0 {
+		errs = append(errs, "pricing.sale_scheduler_interval tidak boleh negatif")
+	}
+	return errs
+}
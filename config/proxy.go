@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ProxyConfig mengatur cara membaca IP client di belakang load balancer. Tanpa pengaturan ini
+// c.IP() adalah alamat load balancer, sehingga batas per IP (login, register, email) berlaku
+// untuk semua client sekaligus.
+//
+// Header hanya dibaca jika request datang dari salah satu TrustedProxies (IP atau CIDR).
+// Fiber mengambil alamat pertama di header, jadi gunakan header yang ditimpa load balancer
+// (misalnya X-Real-IP atau header khusus penyedia). X-Forwarded-For hanya aman jika load
+// balancer menimpanya, bukan menambahkan alamat ke nilai dari client.
+type ProxyConfig struct {
+	Header         string   `json:"header" yaml:"header"`
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies"`
+}
+
+func (p ProxyConfig) validate() []string {
+	var errs []string
+	// Tanpa daftar proxy tepercaya Fiber mempercayai header dari siapa saja
+	if p.Header != "" && len(p.TrustedProxies) == 0 {
+		errs = append(errs, "proxy.trusted_proxies wajib diisi jika proxy.header diisi (TRUSTED_PROXIES)")
+	}
+	for _, proxy := range p.TrustedProxies {
+		if strings.Contains(proxy, "/") {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Sprintf("proxy.trusted_proxies: CIDR %q tidak valid", proxy))
+			}
+		} else if net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Sprintf("proxy.trusted_proxies: IP %q tidak valid", proxy))
+		}
+	}
+	return errs
+}
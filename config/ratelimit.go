@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Store rate limiter yang didukung
+const (
+	LimiterStoreMemory = "memory"
+	LimiterStoreMongo  = "mongo"
+)
+
+// RateLimitRule membatasi jumlah request dalam sliding window
+type RateLimitRule struct {
+	Limit  int      `json:"limit" yaml:"limit"`
+	Window Duration `json:"window" yaml:"window"`
+}
+
+// LockoutConfig mengatur penguncian akun progresif setelah login gagal berulang.
+// Kunci pertama berlaku Base, lalu berlipat dua setiap kegagalan berikutnya sampai Max.
+type LockoutConfig struct {
+	Threshold int      `json:"threshold" yaml:"threshold"`
+	Base      Duration `json:"base" yaml:"base"`
+	Max       Duration `json:"max" yaml:"max"`
+}
+
+// RateLimitConfig mengatur throttling endpoint autentikasi
+type RateLimitConfig struct {
+	// Store: memory (per proses) atau mongo (dibagi semua proses dan instance)
+	Store           string        `json:"store" yaml:"store"`
+	LoginPerIP      RateLimitRule `json:"login_per_ip" yaml:"login_per_ip"`
+	LoginPerAccount RateLimitRule `json:"login_per_account" yaml:"login_per_account"`
+	RegisterPerIP   RateLimitRule `json:"register_per_ip" yaml:"register_per_ip"`
+	// EmailPerIP membatasi endpoint publik yang mengirim email (verifikasi, lupa password)
+	EmailPerIP RateLimitRule `json:"email_per_ip" yaml:"email_per_ip"`
+	Lockout    LockoutConfig `json:"lockout" yaml:"lockout"`
+}
+
+func defaultRateLimit() RateLimitConfig {
+	return RateLimitConfig{
+		Store:           LimiterStoreMemory,
+		LoginPerIP:      RateLimitRule{Limit: 20, Window: Duration(time.Minute)},
+		LoginPerAccount: RateLimitRule{Limit: 10, Window: Duration(15 * time.Minute)},
+		RegisterPerIP:   RateLimitRule{Limit: 5, Window: Duration(time.Hour)},
+		EmailPerIP:      RateLimitRule{Limit: 5, Window: Duration(time.Hour)},
+		Lockout: LockoutConfig{
+			Threshold: 5,
+			Base:      Duration(time.Minute),
+			Max:       Duration(time.Hour),
+		},
+	}
+}
+
+// LockDuration menghitung lama kunci untuk jumlah kegagalan berturut-turut, 0 jika belum dikunci
+func (l LockoutConfig) LockDuration(failures int) time.Duration {
+	if l.Threshold <= 0 || failures < l.Threshold {
+		return 0
+	}
+	d := l.Base.Std()
+	for i := l.Threshold; i < failures && d < l.Max.Std(); i++ {
+		d *= 2
+	}
+	if d > l.Max.Std() {
+		d = l.Max.Std()
+	}
+	return d
+}
+
+func (r RateLimitConfig) validate() []string {
+	var errs []string
+	if r.Store != LimiterStoreMemory && r.Store != LimiterStoreMongo {
+		errs = append(errs, fmt.Sprintf("rate_limit.store harus memory atau mongo, didapat %q", r.Store))
+	}
+	rules := map[string]RateLimitRule{
+		"login_per_ip":      r.LoginPerIP,
+		"login_per_account": r.LoginPerAccount,
+		"register_per_ip":   r.RegisterPerIP,
+		"email_per_ip":      r.EmailPerIP,
+	}
+	for name, rule := range rules {
+		if rule.Limit <= 0 || rule.Window <= 0 {
+			errs = append(errs, fmt.Sprintf("rate_limit.%s: limit dan window harus lebih dari 0", name))
+		}
+	}
+	if r.Lockout.Threshold > 0 && (r.Lockout.Base <= 0 || r.Lockout.Max < r.Lockout.Base) {
+		errs = append(errs, "rate_limit.lockout: base harus lebih dari 0 dan max tidak boleh lebih kecil dari base")
+	}
+	return errs
+}
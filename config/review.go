@@ -0,0 +1,13 @@
+package config
+
+// ReviewConfig mengatur siapa yang boleh menulis ulasan produk
+type ReviewConfig struct {
+	// RequireVerifiedPurchase menolak ulasan dari pengguna yang tidak punya pesanan selesai untuk
+	// produk tersebut (bawaan true). Jika false, ulasan tetap diterima dan ditandai verified_purchase
+	// sesuai pesanan.
+	RequireVerifiedPurchase bool `json:"require_verified_purchase" yaml:"require_verified_purchase"`
+}
+
+func defaultReview() ReviewConfig {
+	return ReviewConfig{RequireVerifiedPurchase: true}
+}
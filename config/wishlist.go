@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// WishlistConfig mengatur notifikasi penurunan harga produk di wishlist
+type WishlistConfig struct {
+	// PriceDropInterval adalah jarak antar pemeriksaan harga. 0 menonaktifkan notifikasi.
+	PriceDropInterval Duration `json:"price_drop_interval" yaml:"price_drop_interval"`
+}
+
+func defaultWishlist() WishlistConfig {
+	return WishlistConfig{PriceDropInterval: Duration(time.Hour)}
+}
+
+func (c WishlistConfig) validate() []string {
+	var errs []string
+	if c.PriceDropInterval < 0 {
+		errs = append(errs, "wishlist.price_drop_interval tidak boleh negatif")
+	}
+	return errs
+}
@@ -2,51 +2,20 @@ package controllers
 
 import (
 	"context"
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	models "github.com/ChekoutGobiz/BackendChekout/model"
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var productCollection *mongo.Collection
-
-// Initialize MongoDB connection
-func init() {
-	// Load .env file
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	// Ambil MONGODB_URI dari environment
-	mongoURI := os.Getenv("MONGODB_URI")
-
-	// Opsi koneksi MongoDB
-	clientOptions := options.Client().ApplyURI(mongoURI)
-	client, err := mongo.Connect(context.TODO(), clientOptions)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Cek koneksi MongoDB
-	err = client.Ping(context.TODO(), nil)
-	if err != nil {
-		log.Fatal("Failed to connect to MongoDB:", err)
-	}
-
-	log.Println("MongoDB connection established successfully!")
-
-	// Initialize product collection
-	productCollection = client.Database("jajankuy").Collection("products")
-}
-
 // CreateProduct handles the creation of a new product
 func CreateProduct(c *fiber.Ctx) error {
 	// Middleware for token verification already handled in routes.go
@@ -58,22 +27,73 @@ func CreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	// Jika tidak ada harga yang diberikan, set default harga ke 0
-	if product.DiscountPrice <= 0 || product.OriginalPrice <= 0 {
+	product.SKU = strings.TrimSpace(product.SKU)
+	if len(product.SKU) > models.MaxSKULength {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Prices must be greater than zero",
+			"error": fmt.Sprintf("SKU must be at most %d characters", models.MaxSKULength),
 		})
 	}
 
+	if product.HasVariants() || len(product.Options) > 0 {
+		// Harga dan stok produk bervarian diturunkan dari variannya
+		if err := product.PrepareVariants(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	} else {
+		// Jika tidak ada harga yang diberikan, set default harga ke 0
+		if product.DiscountPrice <= 0 || product.OriginalPrice <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Prices must be greater than zero",
+			})
+		}
+		if product.Stock != nil && *product.Stock < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Stock must not be negative",
+			})
+		}
+	}
+
+	if product.CategoryID != nil {
+		count, err := categoryCollection.CountDocuments(c.UserContext(), bson.M{"_id": *product.CategoryID}, options.Count().SetLimit(1))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to find category",
+			})
+		}
+		if count == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Category not found",
+			})
+		}
+	}
+	product.Tags = models.NormalizeTags(product.Tags)
+	if len(product.Tags) > maxProductTags {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("A product can have at most %d tags", maxProductTags),
+		})
+	}
+
+	// Galeri hanya diisi lewat upload gambar, rating lewat ulasan dan diskon terjadwal lewat /sales
+	product.Images = nil
+	product.Rating = nil
+	product.Sales = nil
+
 	// Tambahkan ID, CreatedAt, dan UpdatedAt
 	product.ID = primitive.NewObjectID()
 	product.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
 	product.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.UserContext(), 10*time.Second)
 	defer cancel()
 
 	_, err := productCollection.InsertOne(ctx, product)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "The SKU or a variant SKU is already used by another product",
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to insert product",
@@ -85,15 +105,21 @@ func CreateProduct(c *fiber.Ctx) error {
 	})
 }
 
-// GetProducts retrieves all products from the database
+// GetProducts retrieves products, optionally filtered by category (including subcategories),
+// tags and price range
 func GetProducts(c *fiber.Ctx) error {
+	filter, err := productFilterFromQuery(c)
+	if err != nil {
+		return err
+	}
+
 	var products []models.Product
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.UserContext(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := productCollection.Find(ctx, bson.M{})
+	cursor, err := productCollection.Find(ctx, filter.BSON())
 	if err != nil {
-		log.Println("Error finding products:", err)
+		slog.ErrorContext(c.UserContext(), "Error finding products", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get products",
 		})
@@ -103,7 +129,7 @@ func GetProducts(c *fiber.Ctx) error {
 	for cursor.Next(ctx) {
 		var product models.Product
 		if err := cursor.Decode(&product); err != nil {
-			log.Println("Error decoding product:", err)
+			slog.ErrorContext(c.UserContext(), "Error decoding product", "error", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to decode product",
 			})
@@ -112,7 +138,7 @@ func GetProducts(c *fiber.Ctx) error {
 	}
 
 	if err := cursor.Err(); err != nil {
-		log.Println("Cursor iteration error:", err)
+		slog.ErrorContext(c.UserContext(), "Cursor iteration error", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Cursor iteration error",
 		})
@@ -122,3 +148,74 @@ func GetProducts(c *fiber.Ctx) error {
 		"products": products,
 	})
 }
+
+// maxProductTags membatasi jumlah tag per produk
+const maxProductTags = models.MaxProductTags
+
+// GetProductFacets mengembalikan jumlah produk per kategori, tag dan rentang harga untuk filter
+// yang sama dengan GetProducts
+func GetProductFacets(c *fiber.Ctx) error {
+	filter, err := productFilterFromQuery(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c.UserContext(), 10*time.Second)
+	defer cancel()
+
+	facets, err := models.FindProductFacets(ctx, productCollection, filter, models.DefaultPriceBuckets)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error aggregating product facets", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get product facets",
+		})
+	}
+	categories, err := models.ListCategories(ctx, categoryCollection)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get categories",
+		})
+	}
+	facets.Categories = models.RollUpCategoryFacets(categories, facets.Categories)
+
+	return c.JSON(fiber.Map{
+		"facets": facets,
+	})
+}
+
+// productFilterFromQuery membaca filter dari query: category (ID atau slug), tags (dipisah koma,
+// produk harus punya semuanya), min_price dan max_price
+func productFilterFromQuery(c *fiber.Ctx) (models.ProductFilter, error) {
+	var filter models.ProductFilter
+	if ref := c.Query("category"); ref != "" {
+		category, err := models.FindCategory(c.UserContext(), categoryCollection, ref)
+		if err == models.ErrCategoryNotFound {
+			return filter, fiber.NewError(fiber.StatusNotFound, "Category not found")
+		}
+		if err != nil {
+			return filter, err
+		}
+		filter.CategoryIDs, err = models.CategorySubtreeIDs(c.UserContext(), categoryCollection, category.ID)
+		if err != nil {
+			return filter, err
+		}
+	}
+	if tags := c.Query("tags"); tags != "" {
+		filter.Tags = models.NormalizeTags(strings.Split(tags, ","))
+	}
+	for _, param := range []struct {
+		name   string
+		target **int64
+	}{{"min_price", &filter.MinPrice}, {"max_price", &filter.MaxPrice}} {
+		name, target := param.name, param.target
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		price, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || price < 0 {
+			return filter, fiber.NewError(fiber.StatusBadRequest, name+" must be a non-negative integer")
+		}
+		*target = &price
+	}
+	return filter, nil
+}
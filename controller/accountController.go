@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// minPasswordLength adalah panjang minimal password baru
+const minPasswordLength = models.MinPasswordLength
+
+// RequestEmailVerification mengirim ulang link verifikasi email.
+// Respons selalu sama agar tidak membocorkan email yang terdaftar.
+func RequestEmailVerification(c *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&req); err != nil || models.NormalizeEmail(req.Email) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+	}
+
+	var user models.User
+	err := userCollection.FindOne(c.UserContext(), bson.M{"email": models.NormalizeEmail(req.Email)}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		slog.ErrorContext(c.UserContext(), "Error finding user", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send verification email"})
+	}
+	if err == nil && !user.EmailVerified {
+		sendVerificationEmail(c.UserContext(), user)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the account exists and is not verified yet, a verification link has been sent",
+	})
+}
+
+// VerifyEmail mengonfirmasi email dengan token dari link verifikasi
+func VerifyEmail(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token is required"})
+	}
+
+	token, err := models.ConsumeActionToken(c.UserContext(), actionTokenCollection, req.Token, models.TokenPurposeVerifyEmail)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired token"})
+	}
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error consuming verification token", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify email"})
+	}
+
+	_, err = userCollection.UpdateOne(c.UserContext(), bson.M{"_id": token.UserID}, bson.M{"$set": bson.M{"email_verified": true}})
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error marking email verified", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify email"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Email verified successfully"})
+}
+
+// ForgotPassword mengirim link reset password.
+// Respons selalu sama agar tidak membocorkan email yang terdaftar.
+func ForgotPassword(c *fiber.Ctx) error {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&req); err != nil || models.NormalizeEmail(req.Email) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+	}
+
+	var user models.User
+	err := userCollection.FindOne(c.UserContext(), bson.M{"email": models.NormalizeEmail(req.Email)}).Decode(&user)
+	if err != nil && err != mongo.ErrNoDocuments {
+		slog.ErrorContext(c.UserContext(), "Error finding user", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send reset email"})
+	}
+	if err == nil {
+		sendActionEmail(c.UserContext(), user, models.TokenPurposeResetPassword, appConfig.Mail.ResetTokenTTL.Std(),
+			"Reset your password", "/reset-password",
+			"We received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\nThe link expires in %s. If you did not request this, you can ignore this email.")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the account exists, a password reset link has been sent",
+	})
+}
+
+// ResetPassword mengganti password dengan token dari link reset. Semua sesi (JWT)
+// dan token email lain milik pengguna dicabut.
+func ResetPassword(c *fiber.Ctx) error {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token is required"})
+	}
+	// Validasi password sebelum token dipakai agar token tidak hangus karena password terlalu pendek
+	if msg := validatePassword(req.Password); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	token, err := models.ConsumeActionToken(c.UserContext(), actionTokenCollection, req.Token, models.TokenPurposeResetPassword)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired token"})
+	}
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error consuming reset token", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+
+	if err := setPassword(c.UserContext(), token.UserID, req.Password); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error resetting password", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reset password"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password has been reset, please log in again"})
+}
+
+// setPassword menyimpan hash password baru, membuka kunci akun, lalu mencabut semua JWT
+// dan token email milik pengguna
+func setPassword(ctx context.Context, userID primitive.ObjectID, password string) error {
+	return models.SetPassword(ctx, userCollection, actionTokenCollection, userID, password)
+}
+
+func validatePassword(password string) string {
+	if len(strings.TrimSpace(password)) < minPasswordLength {
+		return fmt.Sprintf("Password must be at least %d characters", minPasswordLength)
+	}
+	return ""
+}
+
+func sendVerificationEmail(ctx context.Context, user models.User) {
+	sendActionEmail(ctx, user, models.TokenPurposeVerifyEmail, appConfig.Mail.VerifyTokenTTL.Std(),
+		"Verify your email", "/verify-email",
+		"Welcome! Please confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.")
+}
+
+// sendActionEmail membuat token sekali pakai dan mengirim link-nya di background,
+// sehingga waktu respons tidak bergantung pada ada tidaknya akun atau lambatnya SMTP
+func sendActionEmail(ctx context.Context, user models.User, purpose string, ttl time.Duration, subject, path, bodyFormat string) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		token, err := models.CreateActionToken(ctx, actionTokenCollection, user.ID, purpose, ttl)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error creating action token", "purpose", purpose, "error", err)
+			return
+		}
+		link := strings.TrimRight(appConfig.Mail.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+		msg := helper.Message{
+			To:      user.Email,
+			Subject: subject,
+			Body:    fmt.Sprintf(bodyFormat, link, ttl),
+		}
+		if err := mailer.Send(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "Error sending email", "purpose", purpose, "error", err)
+		}
+	}()
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Batas masa berlaku API key
+const (
+	defaultAPIKeyDays = 90
+	maxAPIKeyDays     = 365
+)
+
+// CreateAPIKey membuat API key baru untuk merchant atau admin yang sedang login.
+// Kunci asli hanya dikembalikan sekali di respons ini.
+func CreateAPIKey(c *fiber.Ctx) error {
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid data"})
+	}
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required"})
+	}
+	if len(req.Scopes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "At least one scope is required"})
+	}
+	for _, scope := range req.Scopes {
+		if !models.IsValidAPIKeyScope(scope) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown scope: " + scope})
+		}
+	}
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = defaultAPIKeyDays
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPIKeyDays {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "expires_in_days must be between 1 and 365"})
+	}
+
+	principal := middleware.PrincipalFrom(c)
+	key := models.APIKey{
+		OwnerID:   principal.UserID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, req.ExpiresInDays),
+	}
+	raw, err := models.CreateAPIKey(c.UserContext(), apiKeyCollection, &key)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error creating API key", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error creating API key"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"api_key": key,
+		"key":     raw,
+	})
+}
+
+// ListAPIKeys mengembalikan API key milik pengguna yang sedang login (tanpa kunci aslinya)
+func ListAPIKeys(c *fiber.Ctx) error {
+	keys, err := models.ListAPIKeys(c.UserContext(), apiKeyCollection, middleware.PrincipalFrom(c).UserID)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error listing API keys", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error retrieving API keys"})
+	}
+	return c.Status(fiber.StatusOK).JSON(keys)
+}
+
+// RevokeAPIKey mencabut API key. Pemilik bisa mencabut kuncinya sendiri, admin bisa mencabut kunci siapa pun.
+func RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid API key ID"})
+	}
+
+	principal := middleware.PrincipalFrom(c)
+	filter := bson.M{"_id": id}
+	if principal.Role != models.RoleAdmin {
+		filter["owner_id"] = principal.UserID
+	}
+	revoked, err := models.RevokeAPIKey(c.UserContext(), apiKeyCollection, filter, time.Now())
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error revoking API key", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error revoking API key"})
+	}
+	if !revoked {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+	}
+
+	slog.InfoContext(c.UserContext(), "API key revoked", "api_key_id", id.Hex(), "by", principal.UserID.Hex())
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "API key revoked"})
+}
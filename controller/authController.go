@@ -1,89 +1,89 @@
 package controllers
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"sync"
 	"time"
 
+	"github.com/ChekoutGobiz/BackendChekout/metrics"
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
 	models "github.com/ChekoutGobiz/BackendChekout/model"
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
+	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
-    "go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var client *mongo.Client
+// Pesan yang sama untuk email tidak terdaftar dan password salah agar email tidak bisa ditebak
+const errInvalidCredentials = "Invalid email or password"
 
-func init() {
-	// Load .env file
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	// Ambil MONGODB_URI dari environment
-	mongoURI := os.Getenv("MONGODB_URI")
-
-	// Opsi koneksi MongoDB
-	clientOptions := options.Client().ApplyURI(mongoURI)
-	client, err = mongo.Connect(context.TODO(), clientOptions)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Cek koneksi MongoDB
-	err = client.Ping(context.TODO(), nil)
-	if err != nil {
-		log.Fatal("Failed to connect to MongoDB:", err)
-	}
+// Masa berlaku token perantara 2FA
+const (
+	mfaPendingTTL = 5 * time.Minute
+	mfaEnrollTTL  = 15 * time.Minute
+)
 
-	fmt.Println("MongoDB connection established successfully!")
-}
+// dummyPasswordHash dipakai untuk menyamakan waktu respons login ketika email tidak ditemukan
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
 
 // Register function for user registration
 func Register(c *fiber.Ctx) error {
-	var user models.User
-	if err := c.BodyParser(&user); err != nil {
+	// Hanya field ini yang boleh diisi client; _id, role dan status verifikasi ditentukan server
+	var req struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid data"})
 	}
+	req.Email = models.NormalizeEmail(req.Email)
+	if req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+	}
+	if msg := validatePassword(req.Password); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
 
 	// Hash the password
-    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-    if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error hashing password"})
-    }
-    user.Password = string(hashedPassword) // Pastikan hashedPassword disimpan di database
-    
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error hashing password"})
+	}
 
-	// Generate unique ObjectID
-	user.ID = primitive.NewObjectID()
+	user := models.User{
+		ID:       primitive.NewObjectID(),
+		Name:     req.Name,
+		Email:    req.Email,
+		Password: string(hashedPassword),
+		Role:     models.RoleUser,
+	}
 
 	// Insert user to database
-	collection := client.Database("jajankuy").Collection("users")
-	_, err = collection.InsertOne(context.TODO(), user)
+	_, err = userCollection.InsertOne(c.UserContext(), user)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already registered"})
+	}
 	if err != nil {
-		log.Println("Error inserting user:", err)
+		slog.ErrorContext(c.UserContext(), "Error inserting user", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving user"})
 	}
 
-	// Remove password from response for security
-	user.Password = ""
+	metrics.Registrations.Inc()
+	sendVerificationEmail(c.UserContext(), user)
+	mergeGuestCart(c, user.ID)
 
 	// Return the created user in response
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "User successfully registered",
-		"user": fiber.Map{
-			"_id":   user.ID.Hex(),
-			"name":  user.Name,
-			"email": user.Email,
-		},
+		"user":    user.Response(),
 	})
 }
 
@@ -97,92 +97,216 @@ func Login(c *fiber.Ctx) error {
 	}
 
 	// Query database to find the user by email
-	collection := client.Database("jajankuy").Collection("users")
-	err := collection.FindOne(context.TODO(), bson.M{"email": loginData.Email}).Decode(&storedUser)
+	err := userCollection.FindOne(c.UserContext(), bson.M{"email": models.NormalizeEmail(loginData.Email)}).Decode(&storedUser)
+	if err == mongo.ErrNoDocuments {
+		// Tetap jalankan bcrypt agar waktu respons tidak membedakan email yang tidak terdaftar
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(loginData.Password))
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errInvalidCredentials})
+	}
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+		slog.ErrorContext(c.UserContext(), "Error finding user", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error logging in"})
 	}
 
-	// Debugging: Check passwords
-	log.Printf("Login password: %s", loginData.Password)
-	log.Printf("Stored hashed password: %s", storedUser.Password)
+	// Akun yang terkunci tidak bisa login sampai masa kunci habis. Responsnya sama dengan
+	// password salah, karena email yang tidak terdaftar tidak pernah terkunci dan respons
+	// berbeda akan membocorkan email mana yang terdaftar.
+	now := time.Now()
+	if storedUser.IsLocked(now) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(loginData.Password))
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errInvalidCredentials})
+	}
 
 	// Compare the hashed password with the provided password
-    if err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(loginData.Password)); err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid password"})
-    }
-    
+	if err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(loginData.Password)); err != nil {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		recordFailedLogin(c, storedUser.ID, now)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errInvalidCredentials})
+	}
+
+	// Dengan 2FA aktif, password yang benar hanya menghasilkan token mfa_pending
+	if storedUser.MFAEnabled {
+		mfaToken, err := signJWT(storedUser.Email, storedUser.ID.Hex(), storedUser.Role, middleware.TokenMFAPending, mfaPendingTTL)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error generating JWT token", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+		}
+		metrics.Logins.WithLabelValues("mfa_required").Inc()
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+	}
+
+	// Role yang diwajibkan memakai 2FA harus mendaftar dulu sebelum mendapat token sesi
+	policy, err := models.GetMFAPolicy(c.UserContext(), settingsCollection)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error reading MFA policy", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error logging in"})
+	}
+	if policy.Requires(storedUser.Role) {
+		enrollToken, err := signJWT(storedUser.Email, storedUser.ID.Hex(), storedUser.Role, middleware.TokenMFAEnroll, mfaEnrollTTL)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error generating JWT token", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+		}
+		metrics.Logins.WithLabelValues("mfa_required").Inc()
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":                   "Two-factor authentication must be set up for this account",
+			"mfa_enrollment_required": true,
+			"mfa_token":               enrollToken,
+		})
+	}
+
+	return completeLogin(c, &storedUser)
+}
+
+// LoginMFA menukar token mfa_pending dan kode TOTP (atau recovery code) dengan token sesi
+func LoginMFA(c *fiber.Ctx) error {
+	var req struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "mfa_token and code or recovery_code are required"})
+	}
+
+	claims, err := jwtKeys.Parse(req.MFAToken)
+	if err != nil || middleware.TokenType(claims) != middleware.TokenMFAPending {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+	userID, err := primitive.ObjectIDFromHex(fmt.Sprint(claims["user_id"]))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	var user models.User
+	err = userCollection.FindOne(c.UserContext(), bson.M{"_id": userID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error finding user", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error logging in"})
+	}
+	// Token mfa_pending ikut dicabut oleh reset password atau ganti password
+	issuedAt, _ := claims["iat"].(float64)
+	if !user.MFAEnabled || (user.TokensValidAfter != nil && int64(issuedAt) < user.TokensValidAfter.Unix()) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired MFA token"})
+	}
+
+	// Sama seperti Login, akun terkunci dijawab seperti kode yang salah
+	now := time.Now()
+	if user.IsLocked(now) {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	ok, err := verifySecondFactor(c.UserContext(), &user, req.Code, req.RecoveryCode)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error verifying MFA code", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error logging in"})
+	}
+	if !ok {
+		// Kode salah dihitung sebagai login gagal agar TOTP tidak bisa ditebak
+		metrics.Logins.WithLabelValues("failure").Inc()
+		recordFailedLogin(c, user.ID, now)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	return completeLogin(c, &user)
+}
+
+// completeLogin mereset hitungan login gagal dan menerbitkan token sesi
+func completeLogin(c *fiber.Ctx, user *models.User) error {
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := models.ResetFailedLogins(c.UserContext(), userCollection, user.ID); err != nil {
+			slog.ErrorContext(c.UserContext(), "Error resetting failed logins", "error", err)
+		}
+	}
 
 	// Generate JWT Token
-	token, err := generateJWT(storedUser.Email, storedUser.ID.Hex()) // Convert ObjectID to string
+	token, err := generateJWT(user.Email, user.ID.Hex(), user.Role) // Convert ObjectID to string
 	if err != nil {
-		log.Println("Error generating JWT token:", err)
+		slog.ErrorContext(c.UserContext(), "Error generating JWT token", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
 	}
 
-	// Log the generated token
-	log.Println("Generated JWT token:", token)
+	metrics.Logins.WithLabelValues("success").Inc()
+	mergeGuestCart(c, user.ID)
 
 	// Return the generated token with user data
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"user":  storedUser,
-		"token": token, // Add "Bearer" prefix to the token
+		"user":  user.Response(),
+		"token": token,
 	})
 }
 
-func Logout(c *fiber.Ctx) error {
-    // Mengambil token dari header Authorization
-    token := c.Get("Authorization")
-
-    // Jika token kosong, kembalikan respons error
-    if token == "" {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "No token provided",
-        })
-    }
-
-    // Hapus prefix "Bearer " jika ada
-    token = token[len("Bearer "):]
-
-    // Simpan token yang dicabut ke dalam database MongoDB (blacklist token)
-    collection := client.Database("jajankuy").Collection("blacklisted_tokens")
-    blacklistedToken := models.BlacklistedToken{
-        Token:     token,
-        CreatedAt: time.Now().Unix(),
-    }
-
-    _, err := collection.InsertOne(context.TODO(), blacklistedToken)
-    if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Error blacklisting token",
-        })
-    }
-
-    // Menyampaikan bahwa logout berhasil dan token harus dihapus dari client
-    return c.Status(fiber.StatusOK).JSON(fiber.Map{
-        "message": "Successfully logged out. Token has been blacklisted.",
-    })
+// recordFailedLogin mencatat login gagal dan mengunci akun secara progresif
+func recordFailedLogin(c *fiber.Ctx, userID primitive.ObjectID, now time.Time) {
+	failures, err := models.RecordFailedLogin(c.UserContext(), userCollection, userID)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error recording failed login", "error", err)
+		return
+	}
+	lock := appConfig.RateLimit.Lockout.LockDuration(failures)
+	if lock == 0 {
+		return
+	}
+	if err := models.LockUser(c.UserContext(), userCollection, userID, now.Add(lock)); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error locking user", "error", err)
+		return
+	}
+	slog.WarnContext(c.UserContext(), "Account locked after failed logins", "user_id", userID.Hex(), "failures", failures, "lock", lock.String())
 }
 
-// generateJWT generates a JWT token for the given email and user ID
-func generateJWT(email string, userID string) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
+func Logout(c *fiber.Ctx) error {
+	// Mengambil token dari header Authorization
+	token := c.Get("Authorization")
+
+	// Jika token kosong, kembalikan respons error
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No token provided",
+		})
+	}
 
-	// Set claims for the token
-	claims["email"] = email
-	claims["user_id"] = userID                            // Pass the user ID as a string
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix() // Expiration in 24 hours
+	// Hapus prefix "Bearer " jika ada
+	token = token[len("Bearer "):]
 
-	// Get JWT_SECRET from .env
-	jwtSecret := os.Getenv("JWT_SECRET")
+	// Simpan token yang dicabut ke dalam database MongoDB (blacklist token)
+	blacklistedToken := models.BlacklistedToken{
+		Token:     token,
+		CreatedAt: time.Now().Unix(),
+	}
 
-	// Create the token
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	_, err := blacklistedTokenCollection.InsertOne(c.UserContext(), blacklistedToken)
 	if err != nil {
-		log.Println("Error creating JWT:", err)
-		return "", err
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Error blacklisting token",
+		})
 	}
 
-	return tokenString, nil
-}
\ No newline at end of file
+	// Menyampaikan bahwa logout berhasil dan token harus dihapus dari client
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Successfully logged out. Token has been blacklisted.",
+	})
+}
+
+// generateJWT generates a JWT token for the given email, user ID and role
+func generateJWT(email string, userID string, role string) (string, error) {
+	return signJWT(email, userID, role, middleware.TokenAccess, middleware.AccessTokenTTL)
+}
+
+// signJWT membuat token dengan jenis (claim typ) dan masa berlaku tertentu
+func signJWT(email, userID, role, typ string, ttl time.Duration) (string, error) {
+	return jwtKeys.Sign(jwt.MapClaims{
+		"email":   email,
+		"user_id": userID, // Pass the user ID as a string
+		"role":    role,
+		"typ":     typ,
+	}, ttl)
+}
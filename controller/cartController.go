@@ -1,56 +1,286 @@
 package controllers
 
 import (
-	"context"
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ChekoutGobiz/BackendChekout/metrics"
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
 	models "github.com/ChekoutGobiz/BackendChekout/model"
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
+	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var (
-	cartCollection     *mongo.Collection
-	productsCollection *mongo.Collection // Add this for accessing the product collection
+// Cart token keranjang tamu: cookie untuk browser, header untuk client lain
+const (
+	guestCartCookie = "guest_cart"
+	headerCartToken = "X-Cart-Token"
 )
 
-func init() {
-	// Load .env file
-	err := godotenv.Load()
+// cartOwner adalah pemilik keranjang pada request ini: pengguna login atau tamu
+type cartOwner struct {
+	userID  primitive.ObjectID
+	guestID primitive.ObjectID
+}
+
+func (o *cartOwner) isGuest() bool {
+	return o.userID.IsZero()
+}
+
+// filter mengembalikan filter MongoDB untuk keranjang milik owner
+func (o *cartOwner) filter() bson.M {
+	if o.isGuest() {
+		return bson.M{"guest_id": o.guestID}
+	}
+	return bson.M{"user_id": o.userID}
+}
+
+// touch menambahkan updated_at (dan expires_at untuk tamu) ke $set
+func (o *cartOwner) touch(set bson.M, now time.Time) bson.M {
+	set["updated_at"] = now
+	if o.isGuest() {
+		set["expires_at"] = now.Add(appConfig.Cart.GuestTTL.Std())
+	}
+	return set
+}
+
+// resolveCartOwner menentukan pemilik keranjang dari JWT atau cart token tamu.
+// Jika create bernilai true dan tidak ada keduanya, tamu baru dibuat. Mengembalikan nil jika
+// tidak ada keranjang yang bisa dipakai.
+func resolveCartOwner(c *fiber.Ctx, create bool) (*cartOwner, error) {
+	if p := middleware.PrincipalFrom(c); p != nil {
+		// user_id di query tetap diterima untuk kompatibilitas, tetapi harus milik pemilik token
+		if userID := c.Query("user_id"); userID != "" && userID != p.UserID.Hex() {
+			return nil, fiber.NewError(fiber.StatusForbidden, "Cannot access another user's cart")
+		}
+		return &cartOwner{userID: p.UserID}, nil
+	}
+	if guestID, ok := guestIDFromRequest(c); ok {
+		return &cartOwner{guestID: guestID}, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	return &cartOwner{guestID: primitive.NewObjectID()}, nil
+}
+
+// guestIDFromRequest membaca dan memverifikasi cart token dari header atau cookie
+func guestIDFromRequest(c *fiber.Ctx) (primitive.ObjectID, bool) {
+	token := c.Get(headerCartToken)
+	if token == "" {
+		token = c.Cookies(guestCartCookie)
+	}
+	if token == "" {
+		return primitive.NilObjectID, false
+	}
+	claims, err := jwtKeys.Parse(token)
+	if err != nil || middleware.TokenType(claims) != middleware.TokenGuestCart {
+		return primitive.NilObjectID, false
+	}
+	guestID, err := primitive.ObjectIDFromHex(fmt.Sprint(claims["guest_id"]))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return guestID, true
+}
+
+// issueGuestCartToken mengirim cart token baru (masa berlaku diperpanjang setiap keranjang diubah)
+func issueGuestCartToken(c *fiber.Ctx, guestID primitive.ObjectID) error {
+	ttl := appConfig.Cart.GuestTTL.Std()
+	token, err := jwtKeys.Sign(jwt.MapClaims{
+		"guest_id": guestID.Hex(),
+		"typ":      middleware.TokenGuestCart,
+	}, ttl)
+	if err != nil {
+		return err
+	}
+	c.Set(headerCartToken, token)
+	c.Cookie(&fiber.Cookie{
+		Name:     guestCartCookie,
+		Value:    token,
+		Path:     "/api", // juga dikirim ke /api/login dan /api/register untuk merge
+		MaxAge:   int(ttl.Seconds()),
+		Secure:   true,
+		HTTPOnly: true,
+		// Frontend berada di domain lain sehingga cookie harus dikirim lintas situs
+		SameSite: fiber.CookieSameSiteNoneMode,
+	})
+	return nil
+}
+
+// clearGuestCartToken menghapus cookie cart token setelah keranjang tamu digabung
+func clearGuestCartToken(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     guestCartCookie,
+		Path:     "/api", // juga dikirim ke /api/login dan /api/register untuk merge
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteNoneMode,
+	})
+}
+
+// mergeGuestCart menggabungkan keranjang tamu di request ke keranjang pengguna setelah login atau register.
+// Kegagalan hanya dicatat agar login tetap berhasil.
+func mergeGuestCart(c *fiber.Ctx, userID primitive.ObjectID) {
+	guestID, ok := guestIDFromRequest(c)
+	if !ok {
+		return
+	}
+	ctx := c.UserContext()
+
+	var guest models.Cart
+	err := cartCollection.FindOne(ctx, bson.M{"guest_id": guestID}).Decode(&guest)
+	if err == mongo.ErrNoDocuments {
+		clearGuestCartToken(c)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error finding guest cart", "error", err)
+		return
+	}
+
+	owner := cartOwner{userID: userID}
+	_, err = models.MergeCartItems(ctx, cartCollection, owner.filter(), guest.Items,
+		appConfig.Cart.MergeStrategy, appConfig.Cart.MaxQuantity, owner.touch(bson.M{}, time.Now()))
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		slog.ErrorContext(ctx, "Error merging guest cart", "error", err)
+		return
+	}
+	if _, err := cartCollection.DeleteOne(ctx, bson.M{"_id": guest.ID}); err != nil {
+		slog.ErrorContext(ctx, "Error deleting merged guest cart", "error", err)
 	}
+	clearGuestCartToken(c)
+	slog.InfoContext(ctx, "Guest cart merged", "items", len(guest.Items), "strategy", appConfig.Cart.MergeStrategy)
+}
+
+// ifMatchVersion membaca versi keranjang dari header If-Match ("3" atau W/"3").
+// Mengembalikan nil jika header tidak dikirim, artinya tanpa pemeriksaan versi.
+func ifMatchVersion(c *fiber.Ctx) (*int64, error) {
+	value := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+	value = strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid If-Match header")
+	}
+	return &version, nil
+}
 
-	// Get MONGODB_URI from environment
-	mongoURI := os.Getenv("MONGODB_URI")
+// setCartETag mengirim versi keranjang sebagai ETag untuk dipakai di If-Match berikutnya
+func setCartETag(c *fiber.Ctx, cart *models.Cart) {
+	c.Set(fiber.HeaderETag, fmt.Sprintf(`"%d"`, cart.Version))
+}
 
-	// MongoDB connection options
-	clientOptions := options.Client().ApplyURI(mongoURI)
-	client, err := mongo.Connect(context.TODO(), clientOptions)
+// lineKeyFromRequest membaca produk dari parameter :product_id dan varian dari query variant_id
+func lineKeyFromRequest(c *fiber.Ctx) (models.CartLineKey, error) {
+	var key models.CartLineKey
+	productID, err := primitive.ObjectIDFromHex(c.Params("product_id"))
 	if err != nil {
-		log.Fatal(err)
+		return key, fiber.NewError(fiber.StatusBadRequest, "Invalid product ID format")
+	}
+	key.ProductID = productID
+	if v := c.Query("variant_id"); v != "" {
+		if key.VariantID, err = primitive.ObjectIDFromHex(v); err != nil {
+			return key, fiber.NewError(fiber.StatusBadRequest, "Invalid variant ID format")
+		}
+	}
+	return key, nil
+}
+
+// findCartProduct mengambil produk untuk baris keranjang dan memastikan variannya valid.
+// stock adalah stok produk atau varian itu, nil jika stok tidak dilacak.
+func findCartProduct(c *fiber.Ctx, key models.CartLineKey) (product *models.Product, stock *int, err error) {
+	product, err = findProduct(c, key.ProductID)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, stock, err = product.Resolve(key.VariantID)
+	if err := variantError(err); err != nil {
+		return nil, nil, err
+	}
+	return product, stock, nil
+}
+
+// cartLimit adalah jumlah maksimum satu baris keranjang: Cart.MaxQuantity, atau stok jika lebih kecil
+func cartLimit(stock *int) int {
+	if stock != nil && *stock < appConfig.Cart.MaxQuantity {
+		return max(*stock, 0)
 	}
+	return appConfig.Cart.MaxQuantity
+}
+
+// cartStockError seperti cartError, tetapi melaporkan sisa stok jika batas yang terlampaui
+// berasal dari stok, dengan pesan yang sama seperti PATCH /api/cart
+func cartStockError(c *fiber.Ctx, err error, quantity int, stock *int) error {
+	limited := cartLimit(stock) < appConfig.Cart.MaxQuantity
+	if err == models.ErrCartQuantityLimit && limited && quantity >= 1 && quantity <= appConfig.Cart.MaxQuantity {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("only %d left in stock", *stock),
+		})
+	}
+	return cartError(c, err)
+}
 
-	// Check MongoDB connection
-	err = client.Ping(context.TODO(), nil)
+// findProduct mengambil satu produk, dengan 404 jika tidak ada
+func findProduct(c *fiber.Ctx, id primitive.ObjectID) (*models.Product, error) {
+	var product models.Product
+	err := productCollection.FindOne(c.UserContext(), bson.M{"_id": id}).Decode(&product)
+	if err == mongo.ErrNoDocuments {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Product not found")
+	}
 	if err != nil {
-		log.Fatal("Failed to connect to MongoDB:", err)
+		slog.ErrorContext(c.UserContext(), "Error finding product", "error", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to find product")
 	}
+	return &product, nil
+}
 
-	log.Println("MongoDB connection established successfully!")
+// checkVariant memastikan variantID cocok dengan produk: wajib untuk produk bervarian, kosong untuk yang tidak
+func checkVariant(product *models.Product, variantID primitive.ObjectID) error {
+	_, _, err := product.Resolve(variantID)
+	return variantError(err)
+}
+
+// variantError mengubah error Product.Resolve menjadi respons
+func variantError(err error) error {
+	switch err {
+	case models.ErrVariantRequired:
+		return fiber.NewError(fiber.StatusBadRequest, "variant_id is required for this product")
+	case models.ErrVariantNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Variant not found")
+	}
+	return nil
+}
 
-	// Initialize cart and product collections
-	cartCollection = client.Database("jajankuy").Collection("carts")
-	productsCollection = client.Database("jajankuy").Collection("products")
+// cartError mengubah error operasi keranjang menjadi respons
+func cartError(c *fiber.Ctx, err error) error {
+	switch err {
+	case models.ErrCartNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Cart not found"})
+	case models.ErrCartItemNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Item not found in cart"})
+	case models.ErrCartVersionConflict:
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{"error": "Cart has been modified, reload it and try again"})
+	case models.ErrCartQuantityLimit:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Quantity must be between 1 and %d per item", appConfig.Cart.MaxQuantity),
+		})
+	}
+	slog.ErrorContext(c.UserContext(), "Error updating cart", "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update cart"})
 }
 
-// AddItemToCart adds an item to the user's cart
+// AddItemToCart adds an item to the user's cart, or to a guest cart when not logged in
 func AddItemToCart(c *fiber.Ctx) error {
 	// Parse request body
 	var cartItem models.CartItem
@@ -59,57 +289,37 @@ func AddItemToCart(c *fiber.Ctx) error {
 			"error": "Invalid request data",
 		})
 	}
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
 
-	// Get user_id from query parameter
-	userID := c.Query("user_id")
-	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "User ID is required",
-		})
+	owner, err := resolveCartOwner(c, true)
+	if err != nil {
+		return err
 	}
 
-	// Convert user_id to ObjectID
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	// Produk (dan varian, jika produk punya varian) harus ada sebelum masuk keranjang
+	_, stock, err := findCartProduct(c, cartItem.Key())
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID format",
-		})
+		return err
 	}
 
-	// Find the cart for the user, or create a new one if it doesn't exist
-	var cart models.Cart
-	err = cartCollection.FindOne(context.Background(), bson.M{"user_id": userObjectID}).Decode(&cart)
-	if err != nil && err != mongo.ErrNoDocuments {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to find or create cart",
-		})
+	// $inc atau $push atomik, sehingga dua request bersamaan tidak saling menimpa
+	cart, err := models.AddCartItem(c.UserContext(), cartCollection, owner.filter(), cartItem,
+		cartLimit(stock), owner.touch(bson.M{}, time.Now()), expectedVersion)
+	if err != nil {
+		return cartStockError(c, err, cartItem.Quantity, stock)
 	}
 
-	if err == mongo.ErrNoDocuments {
-		// Cart doesn't exist, create new cart
-		cart = models.Cart{
-			UserID:    userObjectID,
-			Items:     []models.CartItem{},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+	if owner.isGuest() {
+		if err := issueGuestCartToken(c, owner.guestID); err != nil {
+			return err
 		}
 	}
 
-	// Add item to cart
-	cart.AddItem(cartItem)
-
-	// Save the updated cart to the database
-	_, err = cartCollection.UpdateOne(
-		context.Background(),
-		bson.M{"user_id": userObjectID},
-		bson.M{"$set": bson.M{"items": cart.Items, "updated_at": time.Now()}},
-		options.Update().SetUpsert(true), // Upsert option ensures cart is created if it doesn't exist
-	)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update cart",
-		})
-	}
+	metrics.CartAdds.Inc()
+	setCartETag(c, cart)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Item added to cart successfully",
@@ -117,25 +327,20 @@ func AddItemToCart(c *fiber.Ctx) error {
 	})
 }
 
-// GetCart retrieves the user's cart with total price
+// GetCart retrieves the user's or guest's cart with total price
 func GetCart(c *fiber.Ctx) error {
-	userIDStr := c.Query("user_id")
-	if userIDStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "User ID is required",
-		})
-	}
-
-	// Convert userID from string to ObjectID
-	objectID, err := primitive.ObjectIDFromHex(userIDStr)
+	owner, err := resolveCartOwner(c, false)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID format",
+		return err
+	}
+	if owner == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cart not found",
 		})
 	}
 
 	var cart models.Cart
-	err = cartCollection.FindOne(context.TODO(), bson.M{"user_id": objectID}).Decode(&cart)
+	err = cartCollection.FindOne(c.UserContext(), owner.filter()).Decode(&cart)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -148,13 +353,15 @@ func GetCart(c *fiber.Ctx) error {
 	}
 
 	// Calculate the total price of the cart
-	totalPrice, err := cart.TotalPrice(productsCollection)
+	totalPrice, err := cart.TotalPrice(c.UserContext(), productCollection)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to calculate total price",
 		})
 	}
 
+	setCartETag(c, &cart)
+
 	// Send the cart details along with the total price
 	return c.JSON(fiber.Map{
 		"cart":        cart,
@@ -162,88 +369,224 @@ func GetCart(c *fiber.Ctx) error {
 	})
 }
 
-// UpdateCartItem updates the quantity of a specific item in the cart
+// UpdateCartItem updates the quantity of a specific item in the cart. Quantity 0 removes the item.
 func UpdateCartItem(c *fiber.Ctx) error {
-	var cartItem models.CartItem
-	if err := c.BodyParser(&cartItem); err != nil {
+	var req struct {
+		ProductID primitive.ObjectID `json:"product_id"`
+		VariantID primitive.ObjectID `json:"variant_id"`
+		// Pointer agar quantity 0 (hapus baris) bisa dibedakan dari field yang tidak dikirim
+		Quantity *int `json:"quantity"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Quantity == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid data",
 		})
 	}
-
-	// Get userID from query parameter or context
-	userIDStr := c.Query("user_id")
-	if userIDStr == "" {
+	if *req.Quantity < 0 || *req.Quantity > appConfig.Cart.MaxQuantity {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "User ID is required",
+			"error": fmt.Sprintf("Quantity must be between 0 and %d", appConfig.Cart.MaxQuantity),
 		})
 	}
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
 
-	// Convert userID to ObjectID
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	owner, err := resolveCartOwner(c, false)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID format",
+		return err
+	}
+	if owner == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Cart not found",
 		})
 	}
 
-	// Update the item in the cart
-	_, err = cartCollection.UpdateOne(context.TODO(),
-		bson.M{"user_id": userID, "items.product_id": cartItem.ProductID},
-		bson.M{"$set": bson.M{"items.$.quantity": cartItem.Quantity}})
+	// Jumlah baru tidak boleh melebihi stok; quantity 0 hanya menghapus baris sehingga tidak dicek
+	key := models.CartLineKey{ProductID: req.ProductID, VariantID: req.VariantID}
+	var stock *int
+	if *req.Quantity > 0 {
+		if _, stock, err = findCartProduct(c, key); err != nil {
+			return err
+		}
+	}
 
+	// Update the item in the cart
+	cart, err := models.SetCartItemQuantity(c.UserContext(), cartCollection, owner.filter(),
+		key, *req.Quantity, cartLimit(stock), owner.touch(bson.M{}, time.Now()), expectedVersion)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update cart item",
-		})
+		return cartStockError(c, err, *req.Quantity, stock)
 	}
 
+	if owner.isGuest() {
+		if err := issueGuestCartToken(c, owner.guestID); err != nil {
+			return err
+		}
+	}
+
+	setCartETag(c, cart)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// RemoveItemFromCart removes an item from the cart
+// RemoveItemFromCart removes an item from the cart. Variants are selected with ?variant_id=.
 func RemoveItemFromCart(c *fiber.Ctx) error {
-	productIDStr := c.Params("product_id")
-	productID, err := primitive.ObjectIDFromHex(productIDStr)
+	key, err := lineKeyFromRequest(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid product ID format",
-		})
+		return err
+	}
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		return err
 	}
 
-	// Get userID from query parameter or context
-	userIDStr := c.Query("user_id")
-	if userIDStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "User ID is required",
+	owner, err := resolveCartOwner(c, false)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Item not found in cart",
 		})
 	}
 
-	// Convert userID to ObjectID
-	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	// Remove the item from the cart
+	cart, err := models.RemoveCartItem(c.UserContext(), cartCollection, owner.filter(), key,
+		owner.touch(bson.M{}, time.Now()), expectedVersion)
+	if err == models.ErrCartNotFound {
+		err = models.ErrCartItemNotFound
+	}
 	if err != nil {
+		return cartError(c, err)
+	}
+
+	if owner.isGuest() {
+		if err := issueGuestCartToken(c, owner.guestID); err != nil {
+			return err
+		}
+	}
+
+	setCartETag(c, cart)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// maxCartOperations membatasi jumlah operasi dalam satu PATCH /api/cart
+const maxCartOperations = 100
+
+// PatchCart menerapkan sekumpulan operasi (add, set, remove, clear) sekaligus. Batch divalidasi
+// utuh terhadap produk dan stok; jika ada yang gagal, tidak ada perubahan yang disimpan dan
+// error dikembalikan per operasi. Jika berhasil, keranjang dikembalikan dengan harga terbaru.
+func PatchCart(c *fiber.Ctx) error {
+	var req struct {
+		Operations []models.CartOperation `json:"operations"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxCartOperations {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID format",
+			"error": fmt.Sprintf("operations must contain between 1 and %d items", maxCartOperations),
 		})
 	}
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
 
-	// Remove the item from the cart
-	result, err := cartCollection.UpdateOne(
-		context.TODO(),
-		bson.M{"user_id": userID},
-		bson.M{"$pull": bson.M{"items": bson.M{"product_id": productID}}},
-	)
+	owner, err := resolveCartOwner(c, true)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to remove item from cart",
-		})
+		return err
 	}
 
-	if result.ModifiedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Item not found in cart",
+	// Optimistic concurrency: baca, terapkan di memori, simpan hanya jika versi belum berubah.
+	// Tanpa If-Match, konflik dengan request lain dicoba ulang beberapa kali.
+	var cart *models.Cart
+	for attempt := 0; attempt < models.CartWriteAttempts; attempt++ {
+		var current models.Cart
+		err := cartCollection.FindOne(c.UserContext(), owner.filter()).Decode(&current)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return cartError(c, err)
+		}
+		if expectedVersion != nil && current.Version != *expectedVersion {
+			return cartError(c, models.ErrCartVersionConflict)
+		}
+
+		items, opErrs, lastOp := models.ApplyCartOperations(current.Items, req.Operations, appConfig.Cart.MaxQuantity)
+		if len(opErrs) == 0 {
+			ids := make([]primitive.ObjectID, 0, len(lastOp))
+			for key := range lastOp {
+				ids = append(ids, key.ProductID)
+			}
+			products, err := findProductsByID(c, ids)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to find products",
+				})
+			}
+			opErrs = models.ValidateCartProducts(items, products, req.Operations, lastOp)
+		}
+		if len(opErrs) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":  "Cart operations failed, no changes were applied",
+				"errors": opErrs,
+			})
+		}
+
+		cart, err = models.ReplaceCartItems(c.UserContext(), cartCollection, owner.filter(), items,
+			current.Version, owner.touch(bson.M{}, time.Now()))
+		if err == models.ErrCartVersionConflict && expectedVersion == nil {
+			continue
+		}
+		if err != nil {
+			return cartError(c, err)
+		}
+		break
+	}
+	if cart == nil {
+		return cartError(c, models.ErrCartVersionConflict)
+	}
+
+	if owner.isGuest() {
+		if err := issueGuestCartToken(c, owner.guestID); err != nil {
+			return err
+		}
+	}
+
+	totalPrice, err := cart.TotalPrice(c.UserContext(), productCollection)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to calculate total price",
 		})
 	}
 
-	return c.SendStatus(fiber.StatusNoContent)
+	setCartETag(c, cart)
+	return c.JSON(fiber.Map{
+		"cart":        cart,
+		"total_price": totalPrice,
+	})
+}
+
+// findProductsByID mengambil beberapa produk dalam satu query
+func findProductsByID(c *fiber.Ctx, ids []primitive.ObjectID) (map[primitive.ObjectID]models.Product, error) {
+	products := map[primitive.ObjectID]models.Product{}
+	if len(ids) == 0 {
+		return products, nil
+	}
+	list := make(bson.A, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, id)
+	}
+	cursor, err := productCollection.Find(c.UserContext(), bson.M{"_id": bson.M{"$in": list}})
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Product
+	if err := cursor.All(c.UserContext(), &found); err != nil {
+		return nil, err
+	}
+	for _, product := range found {
+		products[product.ID] = product
+	}
+	return products, nil
 }
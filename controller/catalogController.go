@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// catalogImports melacak impor katalog yang berjalan di latar belakang agar bisa dihentikan saat shutdown
+var catalogImports struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// StopCatalogImports membatalkan impor yang sedang berjalan dan menunggu job ditandai gagal,
+// paling lama sampai ctx selesai
+func StopCatalogImports(ctx context.Context) error {
+	if catalogImports.cancel == nil {
+		return nil
+	}
+	catalogImports.cancel()
+	done := make(chan struct{})
+	go func() {
+		catalogImports.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// catalogFormat menentukan format dari query format atau, jika kosong, dari ekstensi nama file
+func catalogFormat(c *fiber.Ctx, fileName string) (string, error) {
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fileName)) {
+		case ".csv":
+			format = models.CatalogCSV
+		case ".jsonl", ".ndjson":
+			format = models.CatalogJSONL
+		}
+	}
+	if format != models.CatalogCSV && format != models.CatalogJSONL {
+		return "", fiber.NewError(fiber.StatusBadRequest, models.ErrUnknownCatalogFormat.Error())
+	}
+	return format, nil
+}
+
+// StartCatalogImport menerima file CSV atau JSONL (multipart field "file") dan mengimpornya di
+// latar belakang dengan upsert berdasarkan SKU. Query dry_run=true hanya memvalidasi. Progres
+// dan error per baris dibaca lewat GetCatalogImport.
+func StartCatalogImport(c *fiber.Ctx) error {
+	// Body tidak di-buffer (lihat middleware.LimitBody); file disalin langsung dari stream ke disk
+	// agar request bisa selesai sebelum impor selesai
+	upload, err := receiveImportFile(c)
+	if err != nil {
+		return err
+	}
+
+	job := models.ImportJob{
+		Format:     upload.format,
+		FileName:   upload.fileName,
+		DryRun:     c.QueryBool("dry_run"),
+		BytesTotal: upload.size,
+		CreatedBy:  middleware.PrincipalFrom(c).UserID,
+	}
+	if err := models.CreateImportJob(c.UserContext(), importJobCollection, &job); err != nil {
+		os.Remove(upload.path)
+		slog.ErrorContext(c.UserContext(), "Error creating import job", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create import job",
+		})
+	}
+
+	// fiber.Ctx tidak boleh dipakai setelah handler selesai, jadi actor diambil sekarang
+	actor := priceActor(c)
+	catalogImports.wg.Add(1)
+	go func() {
+		defer catalogImports.wg.Done()
+		defer os.Remove(upload.path)
+		runCatalogImport(catalogImports.ctx, job, upload.path, actor)
+	}()
+
+	c.Location("/api/admin/catalog/import/" + job.ID.Hex())
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"job": job,
+	})
+}
+
+// importUpload adalah file impor yang sudah disimpan di disk
+type importUpload struct {
+	path, fileName, format string
+	size                   int64
+}
+
+// receiveImportFile membaca multipart field "file" dari stream body ke file sementara, paling
+// banyak Catalog.ImportMaxBytes. Field lain diabaikan. Error yang dikembalikan berupa
+// *fiber.Error; pemanggil menghapus file upload.path jika langkah berikutnya gagal.
+func receiveImportFile(c *fiber.Ctx) (*importUpload, error) {
+	body := c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+	boundary := string(c.Request().Header.MultipartFormBoundary())
+	if boundary == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Multipart field file is required")
+	}
+	reader := multipart.NewReader(body, boundary)
+	var part *multipart.Part
+	for {
+		var err error
+		part, err = reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Multipart field file is required")
+		}
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid multipart body")
+		}
+		if part.FormName() == "file" {
+			break
+		}
+	}
+	defer part.Close()
+
+	upload := &importUpload{fileName: part.FileName()}
+	var err error
+	if upload.format, err = catalogFormat(c, upload.fileName); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "catalog-import-*")
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error creating import file", "error", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to store import file")
+	}
+	upload.path = tmp.Name()
+	maxBytes := appConfig.Catalog.ImportMaxBytes
+	upload.size, err = io.Copy(tmp, io.LimitReader(part, int64(maxBytes)+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err != nil:
+		os.Remove(upload.path)
+		slog.WarnContext(c.UserContext(), "Error receiving import file", "error", err)
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Failed to read import file")
+	case upload.size > int64(maxBytes):
+		os.Remove(upload.path)
+		return nil, fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("Import file must be at most %d bytes", maxBytes))
+	}
+	return upload, nil
+}
+
+// runCatalogImport menjalankan satu job impor dan menyimpan progres serta hasilnya
+func runCatalogImport(ctx context.Context, job models.ImportJob, path string, actor models.PriceActor) {
+	log := slog.With("job_id", job.ID.Hex())
+	if err := models.StartImportJob(ctx, importJobCollection, job.ID); err != nil {
+		log.ErrorContext(ctx, "Error starting import job", "error", err)
+	}
+	importer := models.CatalogImporter{
+		Products:   productCollection,
+		Categories: categoryCollection,
+		History:    priceHistoryCollection,
+		DryRun:     job.DryRun,
+		Actor:      actor,
+		Progress: func(stats models.ImportStats) {
+			if err := models.UpdateImportJobProgress(ctx, importJobCollection, job.ID, stats); err != nil && ctx.Err() == nil {
+				log.WarnContext(ctx, "Error saving import progress", "error", err)
+			}
+		},
+	}
+
+	var stats *models.ImportStats
+	f, err := os.Open(path)
+	if err == nil {
+		stats, err = importer.Import(ctx, bufio.NewReader(f), job.Format)
+		f.Close()
+	}
+	if stats == nil {
+		stats = &models.ImportStats{Errors: []models.ImportRowError{}}
+	}
+	if ctx.Err() != nil {
+		err = errors.New("import interrupted by server shutdown")
+	}
+
+	// Hasil tetap disimpan walaupun ctx sudah dibatalkan karena shutdown
+	finishCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := models.FinishImportJob(finishCtx, importJobCollection, job.ID, *stats, err); err != nil {
+		log.ErrorContext(finishCtx, "Error finishing import job", "error", err)
+	}
+	log.InfoContext(finishCtx, "Catalog import finished", "rows", stats.Rows, "failed", stats.Failed, "dry_run", job.DryRun, "error", err)
+}
+
+// GetCatalogImport mengembalikan status, progres dan error per baris job impor
+func GetCatalogImport(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid import job ID format",
+		})
+	}
+	job, err := models.FindImportJob(c.UserContext(), importJobCollection, id)
+	if err == models.ErrImportJobNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Import job not found",
+		})
+	}
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error finding import job", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get import job",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"job":      job,
+		"progress": job.Progress(),
+	})
+}
+
+// ExportCatalog mengalirkan seluruh katalog sebagai CSV (bawaan) atau JSONL, dalam format yang
+// sama dengan impor
+func ExportCatalog(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", models.CatalogCSV))
+	if format != models.CatalogCSV && format != models.CatalogJSONL {
+		return fiber.NewError(fiber.StatusBadRequest, models.ErrUnknownCatalogFormat.Error())
+	}
+	contentType := "text/csv; charset=utf-8"
+	if format == models.CatalogJSONL {
+		contentType = "application/x-ndjson"
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="catalog-%s.%s"`, time.Now().Format("20060102"), format))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// Handler sudah selesai saat body dialirkan, jadi context request tidak bisa dipakai
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		count, err := models.ExportCatalog(ctx, productCollection, categoryCollection, w, format)
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error exporting catalog", "written", count, "error", err)
+		}
+	})
+	return nil
+}
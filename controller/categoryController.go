@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"log/slog"
+	"strings"
+
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// categoryRequest adalah body untuk membuat atau mengubah kategori
+type categoryRequest struct {
+	Name      string              `json:"name"`
+	Slug      string              `json:"slug"`
+	ParentID  *primitive.ObjectID `json:"parent_id"`
+	SortOrder int                 `json:"sort_order"`
+}
+
+// category memvalidasi request dan mengubahnya menjadi Category. Slug dibuat dari nama jika kosong.
+func (r categoryRequest) category() (*models.Category, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Name is required and must be at most 100 characters")
+	}
+	slug := strings.TrimSpace(r.Slug)
+	if slug == "" {
+		slug = models.Slugify(name)
+	}
+	if !models.IsValidSlug(slug) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Slug may only contain lowercase letters, digits and dashes")
+	}
+	return &models.Category{Name: name, Slug: slug, ParentID: r.ParentID, SortOrder: r.SortOrder}, nil
+}
+
+// categoryError mengubah error operasi kategori menjadi respons
+func categoryError(c *fiber.Ctx, err error) error {
+	switch {
+	case err == models.ErrCategoryNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Category not found"})
+	case err == models.ErrCategoryParentNotFound:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parent category not found"})
+	case err == models.ErrCategoryCycle:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Category cannot be moved under itself or its subcategories"})
+	case err == models.ErrCategoryInUse:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Category still has subcategories or products"})
+	case mongo.IsDuplicateKeyError(err):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Slug is already in use"})
+	}
+	slog.ErrorContext(c.UserContext(), "Error updating category", "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update category"})
+}
+
+// GetCategories mengembalikan pohon kategori, diurutkan berdasarkan sort_order lalu nama
+func GetCategories(c *fiber.Ctx) error {
+	categories, err := models.ListCategories(c.UserContext(), categoryCollection)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error listing categories", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get categories",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"categories": models.BuildCategoryTree(categories),
+	})
+}
+
+// CreateCategory membuat kategori baru (admin)
+func CreateCategory(c *fiber.Ctx) error {
+	var req categoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	category, err := req.category()
+	if err != nil {
+		return err
+	}
+	if err := models.CreateCategory(c.UserContext(), categoryCollection, category); err != nil {
+		return categoryError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"category": category,
+	})
+}
+
+// UpdateCategory mengubah nama, slug, urutan atau parent kategori (admin)
+func UpdateCategory(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid category ID format",
+		})
+	}
+	var req categoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	category, err := req.category()
+	if err != nil {
+		return err
+	}
+	category.ID = id
+	if category.ParentID != nil && *category.ParentID == id {
+		return categoryError(c, models.ErrCategoryCycle)
+	}
+	if err := models.UpdateCategory(c.UserContext(), categoryCollection, category); err != nil {
+		return categoryError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"category": category,
+	})
+}
+
+// DeleteCategory menghapus kategori yang sudah tidak punya subkategori maupun produk (admin)
+func DeleteCategory(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid category ID format",
+		})
+	}
+	if err := models.DeleteCategory(c.UserContext(), categoryCollection, productCollection, id); err != nil {
+		return categoryError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/config"
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	appConfig *config.Config
+	mailer    helper.Mailer
+	jwtKeys   *helper.KeySet
+	blobStore helper.BlobStore
+
+	userCollection             *mongo.Collection
+	blacklistedTokenCollection *mongo.Collection
+	actionTokenCollection      *mongo.Collection
+	productCollection          *mongo.Collection
+	cartCollection             *mongo.Collection
+	regionCollection           *mongo.Collection
+	settingsCollection         *mongo.Collection
+	apiKeyCollection           *mongo.Collection
+	listCollection             *mongo.Collection
+	categoryCollection         *mongo.Collection
+	reviewCollection           *mongo.Collection
+	reviewVoteCollection       *mongo.Collection
+	orderCollection            *mongo.Collection
+	priceHistoryCollection     *mongo.Collection
+	importJobCollection        *mongo.Collection
+)
+
+// Init menyiapkan konfigurasi, mailer, kunci JWT, penyimpanan file dan koleksi MongoDB yang dipakai
+// semua controller, lalu memastikan index yang dibutuhkan tersedia. Harus dipanggil sekali sebelum
+// routes dilayani.
+func Init(db *mongo.Database, cfg *config.Config, m helper.Mailer, keys *helper.KeySet, blobs helper.BlobStore) error {
+	appConfig = cfg
+	mailer = m
+	jwtKeys = keys
+	blobStore = blobs
+
+	userCollection = db.Collection("users")
+	blacklistedTokenCollection = db.Collection("blacklisted_tokens")
+	actionTokenCollection = db.Collection("action_tokens")
+	productCollection = db.Collection("products")
+	cartCollection = db.Collection("carts")
+	regionCollection = db.Collection("regions")
+	settingsCollection = db.Collection("settings")
+	apiKeyCollection = db.Collection("api_keys")
+	listCollection = db.Collection("product_lists")
+	categoryCollection = db.Collection("categories")
+	reviewCollection = db.Collection("reviews")
+	reviewVoteCollection = db.Collection("review_votes")
+	// orders belum dikelola aplikasi ini; hanya dibaca untuk menandai ulasan dari pembeli
+	orderCollection = db.Collection("orders")
+	priceHistoryCollection = db.Collection("price_history")
+	importJobCollection = db.Collection("import_jobs")
+	catalogImports.ctx, catalogImports.cancel = context.WithCancel(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return models.EnsureIndexes(ctx, db)
+}
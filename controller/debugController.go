@@ -0,0 +1,10 @@
+package controllers
+
+import "github.com/gofiber/fiber/v2"
+
+// GetConfig menampilkan konfigurasi aktif dengan semua secret disamarkan
+func GetConfig(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"config": appConfig.Redacted(),
+	})
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Healthz adalah liveness check: proses hidup dan bisa melayani request
+func Healthz(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": "ok",
+	})
+}
+
+// Readyz adalah readiness check: MongoDB terjangkau, konfigurasi wajib ada
+// dan aplikasi tidak sedang berhenti
+func Readyz(c *fiber.Ctx) error {
+	checks := fiber.Map{}
+	ready := true
+
+	if config.ShuttingDown() {
+		checks["shutdown"] = "in progress"
+		ready = false
+	} else {
+		checks["shutdown"] = "ok"
+	}
+
+	// Tanpa kunci penanda tangan, login dan refresh token akan gagal semua
+	if err := jwtKeys.CanSign(); err != nil {
+		checks["config"] = err.Error()
+		ready = false
+	} else {
+		checks["config"] = "ok"
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), 2*time.Second)
+	defer cancel()
+	if err := userCollection.Database().Client().Ping(ctx, nil); err != nil {
+		checks["mongodb"] = err.Error()
+		ready = false
+	} else {
+		checks["mongodb"] = "ok"
+	}
+
+	status, state := fiber.StatusOK, "ready"
+	if !ready {
+		status, state = fiber.StatusServiceUnavailable, "not ready"
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"status": state,
+		"checks": checks,
+	})
+}
+
+// Version mengembalikan informasi build
+func Version(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(config.GetBuildInfo())
+}
+
+// JWKS mengembalikan public key untuk memverifikasi token yang diterbitkan aplikasi ini
+func JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(jwtKeys.JWKS())
+}
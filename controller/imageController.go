@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxGalleryImages membatasi jumlah gambar per produk
+const maxGalleryImages = 20
+
+// productImageSizes adalah ukuran turunan yang dibuat untuk setiap gambar produk
+var productImageSizes = []helper.ImageSize{
+	{Name: "thumb", MaxDimension: 200},
+	{Name: "medium", MaxDimension: 800},
+}
+
+// imageError mengubah error galeri menjadi respons
+func imageError(c *fiber.Ctx, err error) error {
+	switch err {
+	case models.ErrProductNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
+	case models.ErrImageNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Image not found"})
+	case models.ErrGalleryChanged:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "image_ids must list every image in the gallery exactly once"})
+	case models.ErrGalleryFull:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": fmt.Sprintf("A product can have at most %d images", maxGalleryImages)})
+	}
+	slog.ErrorContext(c.UserContext(), "Error updating product gallery", "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update product images"})
+}
+
+// UploadProductImage menerima gambar (multipart field "image", opsional "alt"), membuang metadata EXIF,
+// membuat thumbnail dan versi WebP, menyimpannya di BlobStore lalu menambahkannya ke akhir galeri
+func UploadProductImage(c *fiber.Ctx) error {
+	productID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID format",
+		})
+	}
+	header, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Multipart field image is required",
+		})
+	}
+	maxBytes := appConfig.Media.MaxUploadBytes
+	if header.Size > int64(maxBytes) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": fmt.Sprintf("Image must be at most %d bytes", maxBytes),
+		})
+	}
+	if contentType := header.Header.Get(fiber.HeaderContentType); !helper.IsImageContentType(contentType) {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "Image must be JPEG, PNG, GIF or WebP",
+		})
+	}
+
+	ctx := c.UserContext()
+	// Pastikan produk ada sebelum memproses dan mengunggah file
+	count, err := productCollection.CountDocuments(ctx, bson.M{"_id": productID}, options.Count().SetLimit(1))
+	if err != nil {
+		return imageError(c, err)
+	}
+	if count == 0 {
+		return imageError(c, models.ErrProductNotFound)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return imageError(c, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+	file.Close()
+	if err != nil {
+		return imageError(c, err)
+	}
+	if len(data) > maxBytes {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": fmt.Sprintf("Image must be at most %d bytes", maxBytes),
+		})
+	}
+
+	// Tipe file diperiksa ulang dari isinya, bukan hanya dari header yang dikirim client
+	renditions, err := helper.ProcessImage(data, productImageSizes)
+	switch {
+	case errors.Is(err, helper.ErrImageType):
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "Image must be JPEG, PNG, GIF or WebP",
+		})
+	case errors.Is(err, helper.ErrImageTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": "Image dimensions are too large",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Image could not be decoded",
+		})
+	}
+
+	img := models.ProductImage{
+		ID:        primitive.NewObjectID(),
+		Alt:       c.FormValue("alt"),
+		CreatedAt: time.Now(),
+	}
+	for _, r := range renditions {
+		key := fmt.Sprintf("products/%s/%s/%s.%s", productID.Hex(), img.ID.Hex(), r.Name, r.Format)
+		if err := blobStore.Put(ctx, key, r.ContentType, r.Data); err != nil {
+			deleteImageFiles(ctx, img.Files)
+			return imageError(c, err)
+		}
+		img.Files = append(img.Files, models.ImageFile{
+			Name: r.Name, Format: r.Format, URL: blobStore.URL(key),
+			Width: r.Width, Height: r.Height, Key: key,
+		})
+	}
+
+	product, err := models.AddProductImage(ctx, productCollection, productID, img, maxGalleryImages)
+	if err != nil {
+		deleteImageFiles(ctx, img.Files)
+		return imageError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"image":   img,
+		"product": product,
+	})
+}
+
+// ReorderProductImages mengubah urutan galeri; gambar pertama menjadi gambar utama produk
+func ReorderProductImages(c *fiber.Ctx) error {
+	productID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID format",
+		})
+	}
+	var req struct {
+		ImageIDs []primitive.ObjectID `json:"image_ids"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	product, err := models.ReorderProductImages(c.UserContext(), productCollection, productID, req.ImageIDs)
+	if err != nil {
+		return imageError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"product": product,
+	})
+}
+
+// DeleteProductImage menghapus gambar dari galeri beserta semua file-nya
+func DeleteProductImage(c *fiber.Ctx) error {
+	productID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID format",
+		})
+	}
+	imageID, err := primitive.ObjectIDFromHex(c.Params("image_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid image ID format",
+		})
+	}
+	product, removed, err := models.RemoveProductImage(c.UserContext(), productCollection, productID, imageID)
+	if err != nil {
+		return imageError(c, err)
+	}
+	// File dihapus setelah dokumen diperbarui; kegagalan hanya meninggalkan file yatim
+	deleteImageFiles(c.UserContext(), removed.Files)
+	return c.JSON(fiber.Map{
+		"product": product,
+	})
+}
+
+// deleteImageFiles menghapus file gambar dari BlobStore; kegagalan hanya dicatat
+func deleteImageFiles(ctx context.Context, files []models.ImageFile) {
+	for _, f := range files {
+		if err := blobStore.Delete(ctx, f.Key); err != nil {
+			slog.ErrorContext(ctx, "Error deleting image file", "key", f.Key, "error", err)
+		}
+	}
+}
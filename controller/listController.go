@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// listEntry adalah item wishlist/saved beserta harga dan ketersediaan produk saat ini
+type listEntry struct {
+	models.ListItem
+	Product *models.Product `json:"product"`
+	// Price adalah harga yang dibayar jika dibeli sekarang
+	Price     int64 `json:"price"`
+	Available bool  `json:"available"`
+}
+
+// GetList mengembalikan isi wishlist atau daftar saved milik pengguna dengan harga terbaru.
+// Produk yang sudah dihapus tetap ditampilkan dengan product null dan available false.
+func GetList(list string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.PrincipalFrom(c).UserID
+		items, err := models.ListItems(c.UserContext(), listCollection, userID, list)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error listing items", "list", list, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get list",
+			})
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ProductID)
+		}
+		products, err := findProductsByID(c, ids)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to find products",
+			})
+		}
+
+		entries := make([]listEntry, 0, len(items))
+		for _, item := range items {
+			entry := listEntry{ListItem: item}
+			if product, ok := products[item.ProductID]; ok {
+				entry.Product = &product
+				entry.Price, entry.Available = listPricing(&product, item.VariantID)
+			}
+			entries = append(entries, entry)
+		}
+		return c.JSON(fiber.Map{
+			"list":  list,
+			"items": entries,
+		})
+	}
+}
+
+// listPricing mengembalikan harga dan ketersediaan entri daftar: tingkat varian jika varian dipilih,
+// selain itu harga "mulai dari" produk. Varian yang sudah dihapus dianggap tidak tersedia.
+func listPricing(product *models.Product, variantID primitive.ObjectID) (int64, bool) {
+	if variantID.IsZero() {
+		return product.EffectivePrice(), product.Available()
+	}
+	price, stock, err := product.Resolve(variantID)
+	if err != nil {
+		return 0, false
+	}
+	return price, stock == nil || *stock > 0
+}
+
+// AddToList menambahkan produk ke wishlist atau daftar saved. Menambahkan produk yang sudah ada
+// memperbarui quantity dan pengaturan notifikasinya.
+func AddToList(list string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			ProductID       primitive.ObjectID `json:"product_id"`
+			VariantID       primitive.ObjectID `json:"variant_id"`
+			Quantity        int                `json:"quantity"`
+			NotifyPriceDrop bool               `json:"notify_price_drop"`
+		}
+		if err := c.BodyParser(&req); err != nil || req.ProductID.IsZero() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request data",
+			})
+		}
+
+		item := models.ListItem{
+			UserID:    middleware.PrincipalFrom(c).UserID,
+			List:      list,
+			ProductID: req.ProductID,
+			VariantID: req.VariantID,
+		}
+		switch list {
+		case models.ListSaved:
+			if req.Quantity == 0 {
+				req.Quantity = 1
+			}
+			if req.Quantity < 1 || req.Quantity > appConfig.Cart.MaxQuantity {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": fmt.Sprintf("Quantity must be between 1 and %d", appConfig.Cart.MaxQuantity),
+				})
+			}
+			item.Quantity = req.Quantity
+		case models.ListWishlist:
+			item.NotifyPriceDrop = req.NotifyPriceDrop
+		}
+
+		// Daftar saved akan dipindah ke keranjang sehingga varian wajib untuk produk bervarian;
+		// wishlist boleh menyimpan produk tanpa memilih varian
+		product, err := findProduct(c, req.ProductID)
+		if err != nil {
+			return err
+		}
+		if list == models.ListSaved || !req.VariantID.IsZero() {
+			if err := checkVariant(product, req.VariantID); err != nil {
+				return err
+			}
+		}
+		// Harga saat ditambahkan menjadi acuan notifikasi penurunan harga
+		price, available := listPricing(product, item.VariantID)
+		item.NotifiedPrice = price
+
+		saved, err := models.UpsertListItem(c.UserContext(), listCollection, item)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error adding list item", "list", list, "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update list",
+			})
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"item": listEntry{ListItem: *saved, Product: product, Price: price, Available: available},
+		})
+	}
+}
+
+// RemoveFromList menghapus produk dari wishlist atau daftar saved
+func RemoveFromList(list string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, err := lineKeyFromRequest(c)
+		if err != nil {
+			return err
+		}
+		removed, err := models.RemoveListItem(c.UserContext(), listCollection, middleware.PrincipalFrom(c).UserID, list, key)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update list",
+			})
+		}
+		if !removed {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Item not found in list",
+			})
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// SaveCartItemForLater memindahkan satu baris (produk dan varian dari ?variant_id=) dari keranjang ke daftar saved dengan quantity yang sama.
+// Item dihapus dari keranjang lebih dulu (dengan pemeriksaan versi); jika penyimpanan ke daftar gagal,
+// item dikembalikan ke keranjang.
+func SaveCartItemForLater(c *fiber.Ctx) error {
+	key, err := lineKeyFromRequest(c)
+	if err != nil {
+		return err
+	}
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+	userID := middleware.PrincipalFrom(c).UserID
+	owner := &cartOwner{userID: userID}
+	ctx := c.UserContext()
+
+	var current models.Cart
+	err = cartCollection.FindOne(ctx, owner.filter()).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return cartError(c, models.ErrCartItemNotFound)
+	}
+	if err != nil {
+		return cartError(c, err)
+	}
+	if expectedVersion != nil && current.Version != *expectedVersion {
+		return cartError(c, models.ErrCartVersionConflict)
+	}
+	var item *models.CartItem
+	for i := range current.Items {
+		if current.Items[i].Key() == key {
+			item = &current.Items[i]
+			break
+		}
+	}
+	if item == nil {
+		return cartError(c, models.ErrCartItemNotFound)
+	}
+
+	// Versi yang dibaca dipakai sebagai syarat, sehingga quantity yang disimpan sama dengan yang dihapus
+	cart, err := models.RemoveCartItem(ctx, cartCollection, owner.filter(), key,
+		owner.touch(bson.M{}, time.Now()), &current.Version)
+	if err != nil {
+		return cartError(c, err)
+	}
+
+	// Jumlah ditambahkan ke entri saved yang mungkin sudah ada, bukan menimpanya
+	saved, err := models.AddListItemQuantity(ctx, listCollection, models.ListItem{
+		UserID:    userID,
+		List:      models.ListSaved,
+		ProductID: key.ProductID,
+		VariantID: key.VariantID,
+		Quantity:  item.Quantity,
+	}, appConfig.Cart.MaxQuantity)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error saving item for later, restoring cart item", "error", err)
+		if _, err := models.AddCartItem(ctx, cartCollection, owner.filter(), *item,
+			appConfig.Cart.MaxQuantity, owner.touch(bson.M{}, time.Now()), nil); err != nil {
+			slog.ErrorContext(ctx, "Error restoring cart item", "error", err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save item for later",
+		})
+	}
+
+	setCartETag(c, cart)
+	return c.JSON(fiber.Map{
+		"cart": cart,
+		"item": saved,
+	})
+}
+
+// MoveSavedItemToCart memindahkan satu produk (dan varian dari ?variant_id=) dari daftar saved ke keranjang dengan quantity yang tersimpan
+func MoveSavedItemToCart(c *fiber.Ctx) error {
+	key, err := lineKeyFromRequest(c)
+	if err != nil {
+		return err
+	}
+	expectedVersion, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+	userID := middleware.PrincipalFrom(c).UserID
+	owner := &cartOwner{userID: userID}
+	ctx := c.UserContext()
+
+	item, err := models.FindListItem(ctx, listCollection, userID, models.ListSaved, key)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Item not found in list",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to find list item",
+		})
+	}
+
+	product, stock, err := findCartProduct(c, key)
+	if err != nil {
+		return err
+	}
+	if _, available := listPricing(product, key.VariantID); !available {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Product is out of stock",
+		})
+	}
+
+	quantity := item.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+	cart, err := models.AddCartItem(ctx, cartCollection, owner.filter(),
+		models.CartItem{ProductID: key.ProductID, VariantID: key.VariantID, Quantity: quantity},
+		cartLimit(stock), owner.touch(bson.M{}, time.Now()), expectedVersion)
+	if err != nil {
+		return cartStockError(c, err, quantity, stock)
+	}
+
+	// Item sudah di keranjang; jika penghapusan gagal, item hanya tersisa di daftar saved
+	if _, err := models.RemoveListItem(ctx, listCollection, userID, models.ListSaved, key); err != nil {
+		slog.ErrorContext(ctx, "Error removing moved item from saved list", "error", err)
+	}
+
+	setCartETag(c, cart)
+	return c.JSON(fiber.Map{
+		"message": "Item moved to cart",
+		"cart":    cart,
+	})
+}
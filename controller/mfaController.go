@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// EnrollMFA membuat secret TOTP baru dan mengembalikan URI provisioning untuk QR code.
+// 2FA belum aktif sampai kode pertama dikonfirmasi lewat ConfirmMFA.
+func EnrollMFA(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+	if user.MFAEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+
+	secret, err := helper.GenerateTOTPSecret()
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error generating TOTP secret", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error enrolling two-factor authentication"})
+	}
+	if err := models.BeginMFAEnrollment(c.UserContext(), userCollection, user.ID, secret); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error saving TOTP secret", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error enrolling two-factor authentication"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_url": helper.TOTPProvisioningURI(appConfig.AppName, user.Email, secret),
+	})
+}
+
+// ConfirmMFA mengaktifkan 2FA setelah kode pertama dari authenticator benar.
+// Recovery code hanya ditampilkan sekali di respons ini.
+func ConfirmMFA(c *fiber.Ctx) error {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code is required"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+	if user.MFAEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Two-factor authentication is already enabled"})
+	}
+	if user.MFAPendingSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Start enrollment first"})
+	}
+
+	step, ok := helper.ValidateTOTP(user.MFAPendingSecret, req.Code, time.Now())
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	codes, hashes, err := models.GenerateRecoveryCodes()
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error generating recovery codes", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error enabling two-factor authentication"})
+	}
+	if err := models.EnableMFA(c.UserContext(), userCollection, user.ID, user.MFAPendingSecret, step, hashes); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error enabling MFA", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error enabling two-factor authentication"})
+	}
+
+	message := "Two-factor authentication enabled"
+	if c.Locals(middleware.LocalTokenType) == middleware.TokenMFAEnroll {
+		message += ", please log in again"
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":        message,
+		"recovery_codes": codes,
+	})
+}
+
+// DisableMFA mematikan 2FA. Butuh password dan kode 2FA, dan ditolak jika role pengguna mewajibkan 2FA.
+func DisableMFA(c *fiber.Ctx) error {
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid data"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+	if !user.MFAEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+
+	policy, err := models.GetMFAPolicy(c.UserContext(), settingsCollection)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error reading MFA policy", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error disabling two-factor authentication"})
+	}
+	if policy.Requires(user.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Two-factor authentication is required for your role"})
+	}
+
+	// Password dan kode yang salah dihitung seperti login gagal, dan akun terkunci dijawab
+	// seperti password salah, agar endpoint ini tidak bisa dipakai menebak di luar lockout
+	now := time.Now()
+	if user.IsLocked(now) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Password is incorrect"})
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		recordFailedLogin(c, user.ID, now)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Password is incorrect"})
+	}
+	ok, err := verifySecondFactor(c.UserContext(), user, req.Code, req.RecoveryCode)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error verifying MFA code", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error disabling two-factor authentication"})
+	}
+	if !ok {
+		recordFailedLogin(c, user.ID, now)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	if err := models.DisableMFA(c.UserContext(), userCollection, user.ID); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error disabling MFA", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error disabling two-factor authentication"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes mengganti semua recovery code; kode lama tidak berlaku lagi
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Code is required"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+	if !user.MFAEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Two-factor authentication is not enabled"})
+	}
+	ok, err := verifySecondFactor(c.UserContext(), user, req.Code, "")
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error verifying MFA code", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating recovery codes"})
+	}
+	if !ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Invalid authentication code"})
+	}
+
+	codes, hashes, err := models.GenerateRecoveryCodes()
+	if err == nil {
+		err = models.ReplaceRecoveryCodes(c.UserContext(), userCollection, user.ID, hashes)
+	}
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error generating recovery codes", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating recovery codes"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// GetMFAPolicy mengembalikan role yang wajib memakai 2FA (admin)
+func GetMFAPolicy(c *fiber.Ctx) error {
+	policy, err := models.GetMFAPolicy(c.UserContext(), settingsCollection)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error reading MFA policy", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error reading MFA policy"})
+	}
+	if policy.RequiredRoles == nil {
+		policy.RequiredRoles = []string{}
+	}
+	return c.Status(fiber.StatusOK).JSON(policy)
+}
+
+// UpdateMFAPolicy mengatur role yang wajib memakai 2FA (admin)
+func UpdateMFAPolicy(c *fiber.Ctx) error {
+	var policy models.MFAPolicy
+	if err := c.BodyParser(&policy); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid data"})
+	}
+	if policy.RequiredRoles == nil {
+		policy.RequiredRoles = []string{}
+	}
+	for _, role := range policy.RequiredRoles {
+		if role != models.RoleUser && role != models.RoleMerchant && role != models.RoleAdmin {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unknown role: " + role})
+		}
+	}
+
+	if err := models.SetMFAPolicy(c.UserContext(), settingsCollection, policy); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error saving MFA policy", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error saving MFA policy"})
+	}
+	slog.InfoContext(c.UserContext(), "MFA policy updated", "required_roles", policy.RequiredRoles, "by", c.Locals(middleware.LocalUserID))
+	return c.Status(fiber.StatusOK).JSON(policy)
+}
+
+// verifySecondFactor memeriksa kode TOTP (sekali pakai per langkah waktu) atau recovery code
+func verifySecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := helper.ValidateTOTP(user.MFASecret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return models.ClaimTOTPStep(ctx, userCollection, user.ID, step)
+	}
+	if recoveryCode != "" {
+		return models.ConsumeRecoveryCode(ctx, userCollection, user.ID, recoveryCode)
+	}
+	return false, nil
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// pageFromQuery membaca page (mulai dari 1) dan limit (1 sampai maxLimit) dari query
+func pageFromQuery(c *fiber.Ctx, defaultLimit, maxLimit int) (page, limit int, err error) {
+	page, limit = 1, defaultLimit
+	if v := c.Query("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			return 0, 0, fiber.NewError(fiber.StatusBadRequest, "page must be a positive integer")
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		}
+	}
+	return page, limit, nil
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ukuran halaman riwayat harga
+const (
+	defaultPriceHistoryPage = 50
+	maxPriceHistoryPage     = 200
+)
+
+// priceError mengubah error perubahan harga menjadi respons
+func priceError(c *fiber.Ctx, err error) error {
+	switch err {
+	case models.ErrProductNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Product not found"})
+	case models.ErrVariantRequired:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "variant_id is required for this product"})
+	case models.ErrVariantNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Variant not found"})
+	case models.ErrSaleNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sale not found"})
+	case models.ErrInvalidPrice:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case models.ErrSaleActive, models.ErrSaleOverlap, models.ErrSaleFinished:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case models.ErrPriceConflict:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Product has been modified, please retry"})
+	}
+	slog.ErrorContext(c.UserContext(), "Error updating product price", "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update product price"})
+}
+
+// productForPricing membaca :id dan mengambil produknya
+func productForPricing(c *fiber.Ctx) (*models.Product, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid product ID format")
+	}
+	return findProduct(c, id)
+}
+
+// priceActor mengembalikan pelaku perubahan harga dari principal request
+func priceActor(c *fiber.Ctx) models.PriceActor {
+	p := middleware.PrincipalFrom(c)
+	return models.PriceActor{UserID: p.UserID, APIKeyID: p.APIKeyID}
+}
+
+// UpdateProductPrice mengubah harga produk tanpa varian atau satu varian (variant_id) dan
+// mencatatnya di riwayat harga
+func UpdateProductPrice(c *fiber.Ctx) error {
+	var req struct {
+		VariantID     primitive.ObjectID `json:"variant_id"`
+		DiscountPrice int64              `json:"discount_price"`
+		OriginalPrice int64              `json:"original_price"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	product, err := productForPricing(c)
+	if err != nil {
+		return err
+	}
+	change, err := product.SetPrice(req.VariantID, req.DiscountPrice, req.OriginalPrice, time.Now())
+	if err != nil {
+		return priceError(c, err)
+	}
+	if change != nil {
+		err = models.SaveProductPricing(c.UserContext(), productCollection, priceHistoryCollection, product, []models.PriceChange{*change}, priceActor(c))
+		if err != nil {
+			return priceError(c, err)
+		}
+	}
+	return c.JSON(fiber.Map{
+		"product": product,
+	})
+}
+
+// GetPriceHistory mengembalikan riwayat perubahan harga produk, terbaru lebih dulu
+func GetPriceHistory(c *fiber.Ctx) error {
+	productID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID format",
+		})
+	}
+	page, limit, err := pageFromQuery(c, defaultPriceHistoryPage, maxPriceHistoryPage)
+	if err != nil {
+		return err
+	}
+	history, total, err := models.ListPriceHistory(c.UserContext(), priceHistoryCollection, productID, page, limit)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error listing price history", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get price history",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"history": history,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// CreateSale menjadwalkan diskon untuk produk tanpa varian atau satu varian (variant_id).
+// Diskon diterapkan dan dikembalikan oleh penjadwal harga.
+func CreateSale(c *fiber.Ctx) error {
+	var req struct {
+		VariantID     primitive.ObjectID `json:"variant_id"`
+		DiscountPrice int64              `json:"discount_price"`
+		StartsAt      time.Time          `json:"starts_at"`
+		EndsAt        time.Time          `json:"ends_at"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	product, err := productForPricing(c)
+	if err != nil {
+		return err
+	}
+	sale, err := product.AddSale(models.Sale{
+		VariantID:     req.VariantID,
+		DiscountPrice: req.DiscountPrice,
+		StartsAt:      req.StartsAt.UTC(),
+		EndsAt:        req.EndsAt.UTC(),
+		CreatedBy:     middleware.PrincipalFrom(c).UserID,
+	}, time.Now())
+	switch err {
+	case nil:
+	case models.ErrVariantRequired, models.ErrVariantNotFound, models.ErrSaleOverlap:
+		return priceError(c, err)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	created := *sale
+	if err := models.SaveProductPricing(c.UserContext(), productCollection, priceHistoryCollection, product, nil, priceActor(c)); err != nil {
+		return priceError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"sale": created,
+	})
+}
+
+// CancelSale membatalkan diskon terjadwal. Diskon yang sedang berjalan langsung dikembalikan.
+func CancelSale(c *fiber.Ctx) error {
+	saleID, err := primitive.ObjectIDFromHex(c.Params("sale_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sale ID format",
+		})
+	}
+	product, err := productForPricing(c)
+	if err != nil {
+		return err
+	}
+	change, err := product.CancelSale(saleID, time.Now())
+	if err != nil {
+		return priceError(c, err)
+	}
+	var changes []models.PriceChange
+	if change != nil {
+		changes = append(changes, *change)
+	}
+	if err := models.SaveProductPricing(c.UserContext(), productCollection, priceHistoryCollection, product, changes, priceActor(c)); err != nil {
+		return priceError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"product": product,
+	})
+}
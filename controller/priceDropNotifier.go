@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+)
+
+// RunPriceDropNotifier memeriksa harga produk di wishlist setiap interval dan mengirim email
+// saat harga turun di bawah harga acuan. Berjalan sampai ctx dibatalkan; interval 0 menonaktifkannya.
+func RunPriceDropNotifier(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := notifyPriceDrops(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Error checking wishlist price drops", "error", err)
+			}
+		}
+	}
+}
+
+// notifyPriceDrops menjalankan satu putaran pemeriksaan harga
+func notifyPriceDrops(ctx context.Context) error {
+	cursor, err := models.FindPriceWatches(ctx, listCollection)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	sent := 0
+	for cursor.Next(ctx) {
+		var watch models.PriceWatch
+		if err := cursor.Decode(&watch); err != nil {
+			return err
+		}
+		current, notify := watch.PriceChange()
+		if current == watch.NotifiedPrice || (!notify && current < watch.NotifiedPrice) {
+			// Harga sama, atau turun tetapi stok habis: acuan tidak diubah agar notifikasi
+			// tetap dikirim saat stok tersedia lagi
+			continue
+		}
+
+		// Harga naik hanya menggeser acuan; penurunan berikutnya dibandingkan dengan harga baru
+		claimed, err := models.SetNotifiedPrice(ctx, listCollection, watch.ID, watch.NotifiedPrice, current)
+		if err != nil {
+			return err
+		}
+		if !claimed || !notify {
+			continue
+		}
+
+		msg := helper.Message{
+			To:      watch.User.Email,
+			Subject: "Harga produk di wishlist Anda turun",
+			Body: fmt.Sprintf("Halo %s,\n\nHarga %s turun dari %d menjadi %d.\n\nLihat wishlist Anda: %s\n",
+				watch.User.Name, watch.Product.Name, watch.NotifiedPrice, current,
+				strings.TrimRight(appConfig.Mail.LinkBaseURL, "/")+"/wishlist"),
+		}
+		if err := mailer.Send(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "Error sending price drop email", "error", err)
+			// Kembalikan acuan agar penurunan ini dikirim ulang di putaran berikutnya
+			if _, err := models.SetNotifiedPrice(context.WithoutCancel(ctx), listCollection, watch.ID, current, watch.NotifiedPrice); err != nil {
+				slog.ErrorContext(ctx, "Error restoring wishlist notified price", "error", err)
+			}
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		slog.InfoContext(ctx, "Wishlist price drop notifications sent", "count", sent)
+	}
+	return cursor.Err()
+}
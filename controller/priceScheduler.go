@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+)
+
+// RunPriceScheduler menerapkan dan mengembalikan diskon terjadwal setiap interval. Berjalan sampai
+// ctx dibatalkan; interval 0 menonaktifkannya.
+func RunPriceScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := applyDueSales(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Error applying scheduled sales", "error", err)
+			}
+		}
+	}
+}
+
+// applyDueSales menjalankan satu putaran penjadwal. Dengan Prefork setiap child menjalankan
+// penjadwal; compare-and-swap di SaveProductPricing memastikan hanya satu yang menyimpan
+// dan mencatat perubahan.
+func applyDueSales(ctx context.Context, now time.Time) error {
+	products, err := models.FindDueSaleProducts(ctx, productCollection, now)
+	if err != nil {
+		return err
+	}
+	applied := 0
+	for i := range products {
+		product := &products[i]
+		changes, err := product.ApplyDueSales(now)
+		if err != nil {
+			return err
+		}
+		err = models.SaveProductPricing(ctx, productCollection, priceHistoryCollection, product, changes, models.PriceActor{})
+		if err == models.ErrPriceConflict || err == models.ErrProductNotFound {
+			// Produk diubah atau dihapus di tengah jalan; dicoba lagi di putaran berikutnya
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		applied += len(changes)
+	}
+	if applied > 0 {
+		slog.InfoContext(ctx, "Scheduled sale prices applied", "count", applied)
+	}
+	return nil
+}
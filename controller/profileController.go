@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// currentUser mengambil data pengguna pemilik JWT (diisi VerifyJWT)
+func currentUser(c *fiber.Ctx) (*models.User, error) {
+	userID, err := primitive.ObjectIDFromHex(fmt.Sprint(c.Locals(middleware.LocalUserID)))
+	if err != nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	var user models.User
+	if err := userCollection.FindOne(c.UserContext(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// currentUserError mengubah error dari currentUser menjadi respons
+func currentUserError(c *fiber.Ctx, err error) error {
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	}
+	slog.ErrorContext(c.UserContext(), "Error finding user", "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error retrieving user"})
+}
+
+// GetMe mengembalikan profil pengguna yang sedang login
+func GetMe(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(user.Response())
+}
+
+// UpdateMe mengubah nama dan/atau email. Email baru harus diverifikasi ulang.
+func UpdateMe(c *fiber.Ctx) error {
+	var req struct {
+		Name  *string `json:"name"`
+		Email *string `json:"email"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid data"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+
+	set := bson.M{}
+	emailChanged := false
+	if req.Name != nil {
+		set["name"] = *req.Name
+	}
+	if req.Email != nil {
+		email := models.NormalizeEmail(*req.Email)
+		if email == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email is required"})
+		}
+		if email != user.Email {
+			set["email"] = email
+			set["email_verified"] = false
+			emailChanged = true
+		}
+	}
+	if len(set) == 0 {
+		return c.Status(fiber.StatusOK).JSON(user.Response())
+	}
+
+	var updated models.User
+	err = userCollection.FindOneAndUpdate(c.UserContext(),
+		bson.M{"_id": user.ID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if mongo.IsDuplicateKeyError(err) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already registered"})
+	}
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error updating user", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error updating profile"})
+	}
+
+	if emailChanged {
+		// Token verifikasi lama masih menunjuk ke alamat sebelumnya
+		if err := models.RevokeUserActionTokens(c.UserContext(), actionTokenCollection, user.ID); err != nil {
+			slog.ErrorContext(c.UserContext(), "Error revoking action tokens", "error", err)
+		}
+		sendVerificationEmail(c.UserContext(), updated)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(updated.Response())
+}
+
+// ChangePassword mengganti password dengan password lama sebagai konfirmasi.
+// Semua JWT lama dicabut, lalu token baru diterbitkan untuk sesi ini.
+func ChangePassword(c *fiber.Ctx) error {
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid data"})
+	}
+	if msg := validatePassword(req.NewPassword); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Current password is incorrect"})
+	}
+
+	if err := setPassword(c.UserContext(), user.ID, req.NewPassword); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error changing password", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error changing password"})
+	}
+
+	token, err := generateJWT(user.Email, user.ID.Hex(), user.Role)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error generating JWT token", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error generating token"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Password changed successfully",
+		"token":   token,
+	})
+}
+
+// DeleteMe menghapus akun pengguna beserta keranjang, wishlist, API key dan token email miliknya
+func DeleteMe(c *fiber.Ctx) error {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid data"})
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Password is incorrect"})
+	}
+
+	// User dihapus terakhir agar kegagalan di tengah jalan bisa diulang oleh pengguna yang sama
+	if _, err := cartCollection.DeleteMany(c.UserContext(), bson.M{"user_id": user.ID}); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error deleting cart", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+	}
+	if _, err := listCollection.DeleteMany(c.UserContext(), bson.M{"user_id": user.ID}); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error deleting product lists", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+	}
+	if _, err := apiKeyCollection.DeleteMany(c.UserContext(), bson.M{"owner_id": user.ID}); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error deleting API keys", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+	}
+	if _, err := actionTokenCollection.DeleteMany(c.UserContext(), bson.M{"user_id": user.ID}); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error deleting action tokens", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+	}
+	if _, err := userCollection.DeleteOne(c.UserContext(), bson.M{"_id": user.ID}); err != nil {
+		slog.ErrorContext(c.UserContext(), "Error deleting user", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error deleting account"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Account deleted"})
+}
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Batas panjang ulasan dan ukuran halaman daftar ulasan
+const (
+	maxReviewTitle    = 120
+	maxReviewBody     = 5000
+	defaultReviewPage = 20
+	maxReviewPage     = 50
+)
+
+// reviewRequest adalah body untuk membuat atau mengubah ulasan
+type reviewRequest struct {
+	Rating int    `json:"rating"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// validate merapikan dan memeriksa isi ulasan
+func (r *reviewRequest) validate() error {
+	r.Title, r.Body = strings.TrimSpace(r.Title), strings.TrimSpace(r.Body)
+	if r.Rating < 1 || r.Rating > 5 {
+		return fiber.NewError(fiber.StatusBadRequest, "Rating must be between 1 and 5")
+	}
+	if utf8.RuneCountInString(r.Title) > maxReviewTitle {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Title must be at most %d characters", maxReviewTitle))
+	}
+	if utf8.RuneCountInString(r.Body) > maxReviewBody {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Body must be at most %d characters", maxReviewBody))
+	}
+	return nil
+}
+
+// reviewError mengubah error operasi ulasan menjadi respons
+func reviewError(c *fiber.Ctx, err error) error {
+	switch err {
+	case models.ErrReviewNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Review not found"})
+	case models.ErrReviewExists:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You have already reviewed this product"})
+	case models.ErrReviewForbidden:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Forbidden"})
+	case models.ErrAlreadyVoted:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You have already marked this review as helpful"})
+	case models.ErrVoteNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Vote not found"})
+	}
+	slog.ErrorContext(c.UserContext(), "Error updating review", "error", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update review"})
+}
+
+// reviewIDParam membaca :id sebagai ID ulasan
+func reviewIDParam(c *fiber.Ctx) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return id, fiber.NewError(fiber.StatusBadRequest, "Invalid review ID format")
+	}
+	return id, nil
+}
+
+// reviewQueryFromRequest membaca sort (newest atau helpful), rating, page dan limit dari query
+func reviewQueryFromRequest(c *fiber.Ctx) (models.ReviewQuery, error) {
+	q := models.ReviewQuery{Sort: c.Query("sort", models.ReviewSortNewest)}
+	if q.Sort != models.ReviewSortNewest && q.Sort != models.ReviewSortHelpful {
+		return q, fiber.NewError(fiber.StatusBadRequest, "sort must be newest or helpful")
+	}
+	if v := c.Query("rating"); v != "" {
+		rating, err := strconv.Atoi(v)
+		if err != nil || rating < 1 || rating > 5 {
+			return q, fiber.NewError(fiber.StatusBadRequest, "rating must be between 1 and 5")
+		}
+		q.Rating = rating
+	}
+	var err error
+	q.Page, q.Limit, err = pageFromQuery(c, defaultReviewPage, maxReviewPage)
+	return q, err
+}
+
+// GetProductReviews mengembalikan ulasan yang tampil untuk satu produk beserta ringkasan ratingnya
+func GetProductReviews(c *fiber.Ctx) error {
+	productID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID format",
+		})
+	}
+	q, err := reviewQueryFromRequest(c)
+	if err != nil {
+		return err
+	}
+	product, err := findProduct(c, productID)
+	if err != nil {
+		return err
+	}
+	q.ProductID = &productID
+	q.Status = models.ReviewPublished
+
+	reviews, total, err := models.ListReviews(c.UserContext(), reviewCollection, q)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error listing reviews", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get reviews",
+		})
+	}
+	rating := product.Rating
+	if rating == nil {
+		rating = models.NewRatingSummary()
+	}
+	return c.JSON(fiber.Map{
+		"rating":  rating,
+		"reviews": reviews,
+		"total":   total,
+		"page":    q.Page,
+		"limit":   q.Limit,
+	})
+}
+
+// CreateReview menyimpan ulasan pengguna untuk produk. Satu pengguna hanya bisa mengulas satu produk
+// sekali; ulasan berikutnya diubah lewat UpdateReview.
+func CreateReview(c *fiber.Ctx) error {
+	productID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID format",
+		})
+	}
+	var req reviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+	if _, err := findProduct(c, productID); err != nil {
+		return err
+	}
+	user, err := currentUser(c)
+	if err != nil {
+		return currentUserError(c, err)
+	}
+
+	verified, err := models.HasPurchased(c.UserContext(), orderCollection, user.ID, productID)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error checking purchase", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check purchase history",
+		})
+	}
+	if !verified && appConfig.Review.RequireVerifiedPurchase {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only customers who have ordered this product can review it",
+		})
+	}
+
+	review := models.Review{
+		ProductID:        productID,
+		UserID:           user.ID,
+		AuthorName:       user.Name,
+		Rating:           req.Rating,
+		Title:            req.Title,
+		Body:             req.Body,
+		VerifiedPurchase: verified,
+	}
+	if err := models.CreateReview(c.UserContext(), reviewCollection, productCollection, &review); err != nil {
+		return reviewError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"review": review,
+	})
+}
+
+// UpdateReview mengubah rating dan isi ulasan milik pengguna yang sedang login
+func UpdateReview(c *fiber.Ctx) error {
+	id, err := reviewIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req reviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+	userID := middleware.PrincipalFrom(c).UserID
+	review, err := models.UpdateReview(c.UserContext(), reviewCollection, productCollection, id, userID, req.Rating, req.Title, req.Body)
+	if err != nil {
+		return reviewError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"review": review,
+	})
+}
+
+// ReplyToReview menyimpan atau mengganti balasan merchant untuk ulasan (merchant dan admin)
+func ReplyToReview(c *fiber.Ctx) error {
+	id, err := reviewIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" || utf8.RuneCountInString(body) > maxReviewBody {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Reply is required and must be at most %d characters", maxReviewBody),
+		})
+	}
+	review, err := models.ReplyToReview(c.UserContext(), reviewCollection, id, middleware.PrincipalFrom(c).UserID, body)
+	if err != nil {
+		return reviewError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"review": review,
+	})
+}
+
+// VoteReviewHelpful menandai ulasan sebagai membantu, sekali per pengguna
+func VoteReviewHelpful(c *fiber.Ctx) error {
+	id, err := reviewIDParam(c)
+	if err != nil {
+		return err
+	}
+	review, err := models.VoteHelpful(c.UserContext(), reviewCollection, reviewVoteCollection, id, middleware.PrincipalFrom(c).UserID)
+	if err == models.ErrReviewForbidden {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You cannot vote on your own review",
+		})
+	}
+	if err != nil {
+		return reviewError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"review": review,
+	})
+}
+
+// UnvoteReviewHelpful menarik kembali tanda membantu
+func UnvoteReviewHelpful(c *fiber.Ctx) error {
+	id, err := reviewIDParam(c)
+	if err != nil {
+		return err
+	}
+	review, err := models.UnvoteHelpful(c.UserContext(), reviewCollection, reviewVoteCollection, id, middleware.PrincipalFrom(c).UserID)
+	if err != nil {
+		return reviewError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"review": review,
+	})
+}
+
+// GetModerationReviews mengembalikan ulasan untuk moderasi (admin), bisa difilter dengan
+// status (published atau hidden), flagged=true dan product_id
+func GetModerationReviews(c *fiber.Ctx) error {
+	q, err := reviewQueryFromRequest(c)
+	if err != nil {
+		return err
+	}
+	switch q.Status = c.Query("status"); q.Status {
+	case "", models.ReviewPublished, models.ReviewHidden:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be published or hidden",
+		})
+	}
+	q.FlaggedOnly = c.QueryBool("flagged")
+	if ref := c.Query("product_id"); ref != "" {
+		productID, err := primitive.ObjectIDFromHex(ref)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid product ID format",
+			})
+		}
+		q.ProductID = &productID
+	}
+
+	reviews, total, err := models.ListReviews(c.UserContext(), reviewCollection, q)
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Error listing reviews", "error", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get reviews",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"reviews": reviews,
+		"total":   total,
+		"page":    q.Page,
+		"limit":   q.Limit,
+	})
+}
+
+// ModerateReview menyembunyikan, menampilkan kembali atau menandai ulasan (admin).
+// Ulasan yang disembunyikan dikeluarkan dari ringkasan rating produk.
+func ModerateReview(c *fiber.Ctx) error {
+	id, err := reviewIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Hidden  bool   `json:"hidden"`
+		Flagged bool   `json:"flagged"`
+		Note    string `json:"note"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+	review, err := models.ModerateReview(c.UserContext(), reviewCollection, productCollection, id, req.Hidden, req.Flagged, strings.TrimSpace(req.Note))
+	if err != nil {
+		return reviewError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"review": review,
+	})
+}
@@ -2,31 +2,411 @@ package helper
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func PostStructWithToken[T any](tokenkey string, tokenvalue string, structname interface{}, urltarget string) (result T, err error) {
-	client := http.Client{}
-	mJson, _ := json.Marshal(structname)
-	req, err := http.NewRequest("POST", urltarget, bytes.NewBuffer(mJson))
+const tracerName = "github.com/ChekoutGobiz/BackendChekout/helper"
+
+// ErrCircuitOpen dikembalikan ketika circuit breaker untuk host tujuan sedang terbuka
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// HTTPError adalah error untuk response non-2xx, lengkap dengan body dari server
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	body := e.Body
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return fmt.Sprintf("%s %s: status %d: %s", e.Method, e.URL, e.StatusCode, bytes.TrimSpace(body))
+}
+
+// Client adalah HTTP client untuk semua integrasi keluar.
+// Mendukung timeout per panggilan, retry dengan jittered backoff untuk request idempotent,
+// dan circuit breaker per host.
+type Client struct {
+	HTTPClient *http.Client
+	// Timeout default per panggilan, bisa ditimpa dengan WithTimeout
+	Timeout time.Duration
+	// MaxRetries adalah jumlah percobaan ulang setelah percobaan pertama gagal
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Breaker terbuka setelah BreakerThreshold kegagalan berturut-turut ke host yang sama
+	// dan menolak request selama BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// NewClient membuat Client dengan nilai default yang aman
+func NewClient() *Client {
+	return &Client{
+		HTTPClient:       &http.Client{},
+		Timeout:          10 * time.Second,
+		MaxRetries:       2,
+		BaseBackoff:      200 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// DefaultClient dipakai oleh fungsi *StructWithToken
+var DefaultClient = NewClient()
+
+type callOptions struct {
+	header  http.Header
+	timeout time.Duration
+}
+
+// CallOption mengatur satu panggilan HTTP
+type CallOption func(*callOptions)
+
+// WithHeader menambahkan header ke request
+func WithHeader(key, value string) CallOption {
+	return func(o *callOptions) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithTimeout menimpa timeout default Client untuk satu panggilan
+func WithTimeout(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = d
+	}
+}
+
+// Do mengirim request dan mengembalikan body response untuk status 2xx.
+// Response non-2xx dikembalikan sebagai *HTTPError.
+func (c *Client) Do(ctx context.Context, method, urltarget string, body interface{}, opts ...CallOption) ([]byte, error) {
+	o := callOptions{header: http.Header{}, timeout: c.Timeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("marshal request body: %w", err)
+		}
+	}
+
+	u, err := url.Parse(urltarget)
 	if err != nil {
-		return
+		return nil, err
+	}
+	breaker := c.breaker(u.Host)
+
+	retryable := isIdempotent(method) || o.header.Get("Idempotency-Key") != ""
+	for attempt := 0; ; attempt++ {
+		if !breaker.allow(time.Now()) {
+			return nil, fmt.Errorf("%s %s: %w", method, urltarget, ErrCircuitOpen)
+		}
+
+		respBody, retryAfter, err := c.attempt(ctx, method, urltarget, payload, o)
+		if err != nil && ctx.Err() != nil {
+			// Dibatalkan pemanggil: tidak ada hasil dari upstream yang bisa dicatat
+			breaker.release()
+			return nil, ctx.Err()
+		}
+		breaker.record(err == nil || !isServerFailure(err), time.Now())
+		if err == nil {
+			return respBody, nil
+		}
+		if !retryable || attempt >= c.MaxRetries || !isRetryable(err) {
+			return nil, err
+		}
+
+		// Retry-After dihormati sampai MaxBackoff; jeda yang melewati deadline ctx tidak ditunggu
+		wait := c.backoff(attempt)
+		if retryAfter > wait {
+			wait = min(retryAfter, c.MaxBackoff)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return nil, err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, urltarget string, payload []byte, o callOptions) ([]byte, time.Duration, error) {
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
 	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add(tokenkey, tokenvalue)
-	resp, err := client.Do(req)
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "HTTP "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLFull(redactURL(urltarget)),
+		),
+	)
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, method, urltarget, reqBody)
 	if err != nil {
-		return
+		return nil, 0, err
+	}
+	// Teruskan trace ke layanan tujuan melalui header traceparent
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range o.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
 	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After")), &HTTPError{
+			Method:     method,
+			URL:        urltarget,
+			StatusCode: resp.StatusCode,
+			Body:       respBody,
+		}
+	}
+	return respBody, 0, nil
+}
+
+// redactURL membuang query string yang sering berisi token sebelum dicatat di span
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	u.RawQuery = ""
+	u.User = nil
+	return u.String()
+}
+
+// backoff menghitung jeda "full jitter": acak antara 0 dan BaseBackoff*2^attempt (dibatasi MaxBackoff)
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.BaseBackoff << attempt
+	if ceiling <= 0 || ceiling > c.MaxBackoff {
+		ceiling = c.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+func (c *Client) breaker(host string) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.breakers == nil {
+		c.breakers = make(map[string]*circuitBreaker)
+	}
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &circuitBreaker{threshold: c.BreakerThreshold, cooldown: c.BreakerCooldown}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isRetryable: error jaringan, 429 dan 502/503/504 layak dicoba ulang
+func isRetryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// isServerFailure menentukan apakah error dihitung sebagai kegagalan oleh circuit breaker.
+// Pembatalan oleh pemanggil tidak pernah sampai ke sini (lihat Do).
+func isServerFailure(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	return true
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow mengizinkan request saat breaker tertutup, atau satu request percobaan (half-open)
+// setelah masa cooldown habis
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// release melepas izin percobaan half-open tanpa mencatat hasil, untuk request yang dibatalkan
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if success {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+// DoJSON mengirim request dan men-decode response JSON ke T
+func DoJSON[T any](ctx context.Context, client *Client, method, urltarget string, body interface{}, opts ...CallOption) (result T, err error) {
+	respBody, err := client.Do(ctx, method, urltarget, body, opts...)
 	if err != nil {
 		return
 	}
-	if err = json.Unmarshal(respBody, &result); err != nil {
+	if len(bytes.TrimSpace(respBody)) == 0 {
 		return
 	}
+	err = json.Unmarshal(respBody, &result)
 	return
 }
+
+func GetJSON[T any](ctx context.Context, client *Client, urltarget string, opts ...CallOption) (T, error) {
+	return DoJSON[T](ctx, client, http.MethodGet, urltarget, nil, opts...)
+}
+
+func PostJSON[T any](ctx context.Context, client *Client, urltarget string, body interface{}, opts ...CallOption) (T, error) {
+	return DoJSON[T](ctx, client, http.MethodPost, urltarget, body, opts...)
+}
+
+func PutJSON[T any](ctx context.Context, client *Client, urltarget string, body interface{}, opts ...CallOption) (T, error) {
+	return DoJSON[T](ctx, client, http.MethodPut, urltarget, body, opts...)
+}
+
+func DeleteJSON[T any](ctx context.Context, client *Client, urltarget string, opts ...CallOption) (T, error) {
+	return DoJSON[T](ctx, client, http.MethodDelete, urltarget, nil, opts...)
+}
+
+// budget adalah batas waktu total satu panggilan: semua percobaan ditambah jeda di antaranya
+func (c *Client) budget() time.Duration {
+	return time.Duration(c.MaxRetries+1)*c.Timeout + time.Duration(c.MaxRetries)*c.MaxBackoff
+}
+
+// legacyContext memberi fungsi *StructWithToken, yang tidak menerima ctx, batas waktu total
+// sehingga pemanggilnya tidak pernah menunggu tanpa batas
+func legacyContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), DefaultClient.budget())
+}
+
+func PostStructWithToken[T any](tokenkey string, tokenvalue string, structname interface{}, urltarget string) (result T, err error) {
+	ctx, cancel := legacyContext()
+	defer cancel()
+	return PostJSON[T](ctx, DefaultClient, urltarget, structname, WithHeader(tokenkey, tokenvalue))
+}
+
+func GetStructWithToken[T any](tokenkey string, tokenvalue string, urltarget string) (result T, err error) {
+	ctx, cancel := legacyContext()
+	defer cancel()
+	return GetJSON[T](ctx, DefaultClient, urltarget, WithHeader(tokenkey, tokenvalue))
+}
+
+func PutStructWithToken[T any](tokenkey string, tokenvalue string, structname interface{}, urltarget string) (result T, err error) {
+	ctx, cancel := legacyContext()
+	defer cancel()
+	return PutJSON[T](ctx, DefaultClient, urltarget, structname, WithHeader(tokenkey, tokenvalue))
+}
+
+func DeleteStructWithToken[T any](tokenkey string, tokenvalue string, urltarget string) (result T, err error) {
+	ctx, cancel := legacyContext()
+	defer cancel()
+	return DeleteJSON[T](ctx, DefaultClient, urltarget, WithHeader(tokenkey, tokenvalue))
+}
@@ -0,0 +1,171 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testClient() *Client {
+	c := NewClient()
+	c.BaseBackoff = time.Millisecond
+	c.MaxBackoff = 5 * time.Millisecond
+	return c
+}
+
+func TestGetJSONRetriesIdempotentFailures(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	result, err := GetJSON[map[string]string](context.Background(), testClient(), srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result["status"] != "ok" || calls != 3 {
+		t.Fatalf("got %v after %d calls", result, calls)
+	}
+}
+
+func TestPostJSONDoesNotRetryAndReturnsHTTPError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("token") != "secret" {
+			t.Errorf("missing token header")
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`{"error":"upstream down"}`))
+	}))
+	defer srv.Close()
+
+	_, err := PostJSON[map[string]string](context.Background(), testClient(), srv.URL, map[string]string{"a": "b"}, WithHeader("token", "secret"))
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected *HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusBadGateway || string(httpErr.Body) != `{"error":"upstream down"}` {
+		t.Fatalf("unexpected HTTPError: %+v", httpErr)
+	}
+	if calls != 1 {
+		t.Fatalf("POST retried %d times", calls)
+	}
+}
+
+func TestCircuitBreakerOpensPerHost(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := testClient()
+	c.MaxRetries = 0
+	c.BreakerThreshold = 2
+	c.BreakerCooldown = time.Hour
+	for i := 0; i < 2; i++ {
+		if _, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if _, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("breaker let %d calls through", calls)
+	}
+}
+
+func TestPerCallTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	c := testClient()
+	c.MaxRetries = 0
+	if _, err := c.Do(context.Background(), http.MethodGet, srv.URL, nil, WithTimeout(10*time.Millisecond)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRetryAfterIsCapped(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	if _, err := testClient().Do(context.Background(), http.MethodGet, srv.URL, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || calls != 2 {
+		t.Fatalf("%d calls in %v", calls, elapsed)
+	}
+
+	// Jeda yang melewati deadline ctx tidak ditunggu
+	calls = 0
+	c := testClient()
+	c.MaxBackoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var httpErr *HTTPError
+	if _, err := c.Do(ctx, http.MethodGet, srv.URL, nil); !errors.As(err, &httpErr) || calls != 1 {
+		t.Fatalf("expected the 503 without waiting, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCanceledCallsDoNotTouchBreaker(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	c := testClient()
+	c.MaxRetries = 0
+	c.BreakerThreshold = 2
+	c.BreakerCooldown = time.Millisecond
+	for i := 0; i < 2; i++ {
+		c.Do(context.Background(), http.MethodGet, srv.URL, nil)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// Probe half-open dibatalkan pemanggil: breaker tidak ditutup, tetapi probe berikutnya diizinkan
+	fail.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Do(ctx, http.MethodGet, srv.URL, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	b := c.breaker(strings.TrimPrefix(srv.URL, "http://"))
+	b.mu.Lock()
+	failures, probing := b.failures, b.probing
+	b.mu.Unlock()
+	if failures != 2 || probing {
+		t.Fatalf("failures = %d, probing = %v", failures, probing)
+	}
+}
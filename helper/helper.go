@@ -16,8 +16,10 @@ func GetAddress() (ipport string, network string) {
 	port := os.Getenv("PORT")
 	network = "tcp4"
 	if port == "" {
-		port = ":8080"
-	} else if port[0:1] != ":" {
+		ipport = ":8080"
+	} else if port[0:1] == ":" {
+		ipport = port
+	} else {
 		ip := os.Getenv("IP")
 		if ip == "" {
 			ipport = ":" + port
@@ -0,0 +1,257 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // decoder GIF untuk image.Decode
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // decoder WebP untuk image.Decode
+)
+
+// Error pemrosesan gambar
+var (
+	ErrImageType        = errors.New("image: unsupported content type")
+	ErrImageTooLarge    = errors.New("image: dimensions too large")
+	ErrWebPNotSupported = errors.New("image: webp encoding requires cgo")
+)
+
+// ImageContentTypes adalah tipe gambar yang diterima untuk upload
+var ImageContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// maxImagePixels menolak gambar yang terlalu besar sebelum di-decode (decompression bomb)
+const maxImagePixels = 40_000_000
+
+// ImageSize adalah ukuran turunan; sisi terpanjang diperkecil menjadi MaxDimension
+type ImageSize struct {
+	Name         string
+	MaxDimension int
+}
+
+// ImageRendition adalah satu file hasil pemrosesan
+type ImageRendition struct {
+	Name        string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// ProcessImage memvalidasi gambar upload lalu meng-encode ulang semuanya tanpa metadata
+// (EXIF, termasuk lokasi GPS, ikut terbuang). Orientasi EXIF diterapkan ke piksel lebih dulu.
+// Hasilnya adalah "original" dan satu rendition per ukuran, masing-masing dalam format dasar
+// (JPEG, atau PNG untuk gambar transparan) dan WebP jika encoder tersedia.
+func ProcessImage(data []byte, sizes []ImageSize) ([]ImageRendition, error) {
+	contentType := http.DetectContentType(data)
+	if !IsImageContentType(contentType) {
+		return nil, ErrImageType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("image: %w", err)
+	}
+
+	img := toNRGBA(decoded)
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	opaque := img.Opaque()
+
+	var out []ImageRendition
+	add := func(name string, img image.Image) error {
+		base, err := encodeBase(name, img, opaque)
+		if err != nil {
+			return err
+		}
+		out = append(out, base)
+		webp, err := EncodeWebP(img)
+		if err == ErrWebPNotSupported {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		out = append(out, ImageRendition{
+			Name: name, Format: "webp", ContentType: "image/webp",
+			Width: img.Bounds().Dx(), Height: img.Bounds().Dy(), Data: webp,
+		})
+		return nil
+	}
+
+	if err := add("original", img); err != nil {
+		return nil, err
+	}
+	for _, size := range sizes {
+		if err := add(size.Name, resize(img, size.MaxDimension)); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func encodeBase(name string, img image.Image, opaque bool) (ImageRendition, error) {
+	var buf bytes.Buffer
+	r := ImageRendition{Name: name, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if opaque {
+		r.Format, r.ContentType = "jpeg", "image/jpeg"
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return r, err
+		}
+	} else {
+		r.Format, r.ContentType = "png", "image/png"
+		if err := png.Encode(&buf, img); err != nil {
+			return r, err
+		}
+	}
+	r.Data = buf.Bytes()
+	return r, nil
+}
+
+// resize memperkecil gambar agar sisi terpanjang tidak melebihi max (tidak pernah memperbesar)
+func resize(img *image.NRGBA, max int) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w <= max && h <= max {
+		return img
+	}
+	if w >= h {
+		w, h = max, h*max/w
+	} else {
+		w, h = w*max/h, max
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, maxInt(w, 1), maxInt(h, 1)))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok && n.Bounds().Min == (image.Point{}) {
+		return n
+	}
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// applyOrientation memutar atau membalik piksel sesuai tag EXIF Orientation (1-8)
+func applyOrientation(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-dx, dy
+			case 3:
+				sx, sy = w-1-dx, h-1-dy
+			case 4:
+				sx, sy = dx, h-1-dy
+			case 5:
+				sx, sy = dy, dx
+			case 6:
+				sx, sy = dy, h-1-dx
+			case 7:
+				sx, sy = w-1-dy, h-1-dx
+			case 8:
+				sx, sy = w-1-dy, dx
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation membaca tag Orientation dari segmen APP1 Exif. Mengembalikan 1 (normal)
+// jika tidak ada atau tidak bisa dibaca.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		// Start of scan: metadata selalu sebelum data gambar
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
+
+// IsImageContentType melaporkan apakah tipe konten (tanpa parameter) termasuk ImageContentTypes
+func IsImageContentType(contentType string) bool {
+	for _, v := range ImageContentTypes {
+		if v == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
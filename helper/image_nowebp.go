@@ -0,0 +1,10 @@
+//go:build !cgo
+
+package helper
+
+import "image"
+
+// EncodeWebP tidak tersedia tanpa cgo; upload tetap berhasil tanpa rendition WebP
+func EncodeWebP(img image.Image) ([]byte, error) {
+	return nil, ErrWebPNotSupported
+}
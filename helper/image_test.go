@@ -0,0 +1,69 @@
+package helper
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withExifOrientation menyisipkan segmen APP1 Exif berisi tag Orientation setelah SOI
+func withExifOrientation(jpg []byte, orientation byte) []byte {
+	tiff := []byte{'M', 'M', 0, 0x2A, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, 0, 0, 0, 0}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+	return append(append(append([]byte{}, jpg[:2]...), segment...), jpg[2:]...)
+}
+
+func TestProcessImageJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 100)), nil)
+	data := withExifOrientation(buf.Bytes(), 6)
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("orientation = %d", jpegOrientation(data))
+	}
+
+	renditions, err := ProcessImage(data, []ImageSize{{Name: "thumb", MaxDimension: 50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]ImageRendition{}
+	for _, r := range renditions {
+		if r.Format == "jpeg" {
+			byName[r.Name] = r
+		}
+		if bytes.Contains(r.Data, []byte("Exif")) {
+			t.Errorf("%s/%s still contains EXIF", r.Name, r.Format)
+		}
+	}
+	// Orientation 6 diputar 90 derajat sehingga lebar dan tinggi bertukar
+	if o := byName["original"]; o.Width != 100 || o.Height != 400 {
+		t.Errorf("original = %dx%d", o.Width, o.Height)
+	}
+	if th := byName["thumb"]; th.Width != 12 || th.Height != 50 {
+		t.Errorf("thumb = %dx%d", th.Width, th.Height)
+	}
+}
+
+func TestProcessImageTransparentPNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	img.Set(1, 1, color.NRGBA{R: 255, A: 128})
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+
+	renditions, err := ProcessImage(buf.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renditions[0].Format != "png" {
+		t.Errorf("transparent image encoded as %s", renditions[0].Format)
+	}
+}
+
+func TestProcessImageRejectsNonImages(t *testing.T) {
+	if _, err := ProcessImage([]byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>"), nil); err != ErrImageType {
+		t.Errorf("err = %v", err)
+	}
+}
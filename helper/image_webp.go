@@ -0,0 +1,14 @@
+//go:build cgo
+
+package helper
+
+import (
+	"image"
+
+	"github.com/chai2010/webp"
+)
+
+// EncodeWebP meng-encode gambar ke WebP lossy dengan libwebp (membutuhkan cgo)
+func EncodeWebP(img image.Image) ([]byte, error) {
+	return webp.EncodeRGBA(img, 80)
+}
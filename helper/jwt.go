@@ -0,0 +1,262 @@
+package helper
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algoritma tanda tangan JWT yang didukung untuk kunci asimetris
+const (
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// jwtLeeway adalah toleransi perbedaan jam antar server untuk exp, nbf dan iat
+const jwtLeeway = 30 * time.Second
+
+type jwtKey struct {
+	id      string
+	alg     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet menandatangani JWT dengan satu kunci aktif dan memverifikasi dengan semua kunci yang terdaftar,
+// sehingga kunci bisa dirotasi tanpa membuat semua pengguna logout: kunci lama cukup dibiarkan
+// terdaftar (tanpa private key) sampai token terakhirnya kedaluwarsa.
+type KeySet struct {
+	Issuer   string
+	Audience string
+
+	keys   map[string]*jwtKey
+	active *jwtKey
+	// legacySecret memverifikasi token HS256 lama tanpa kid selama masa migrasi
+	legacySecret []byte
+}
+
+// NewKeySet membuat KeySet kosong untuk issuer dan audience tertentu
+func NewKeySet(issuer, audience string) *KeySet {
+	return &KeySet{Issuer: issuer, Audience: audience, keys: map[string]*jwtKey{}}
+}
+
+// AddKey mendaftarkan kunci. private boleh nil untuk kunci yang hanya dipakai verifikasi;
+// public boleh nil jika private diberikan. alg kosong ditentukan dari jenis kunci.
+func (k *KeySet) AddKey(id, alg string, private crypto.Signer, public crypto.PublicKey) error {
+	if id == "" {
+		return errors.New("jwt: kid wajib diisi")
+	}
+	if _, exists := k.keys[id]; exists {
+		return fmt.Errorf("jwt: kid %q terdaftar dua kali", id)
+	}
+	if public == nil && private != nil {
+		public = private.Public()
+	}
+
+	key := &jwtKey{id: id, private: private, public: public}
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if alg != "" && alg != JWTAlgRS256 {
+			return fmt.Errorf("jwt: kunci %q adalah RSA, bukan %s", id, alg)
+		}
+		if pub.N.BitLen() < 2048 {
+			return fmt.Errorf("jwt: kunci RSA %q minimal 2048 bit", id)
+		}
+		key.alg, key.method = JWTAlgRS256, jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		if alg != "" && alg != JWTAlgEdDSA {
+			return fmt.Errorf("jwt: kunci %q adalah Ed25519, bukan %s", id, alg)
+		}
+		key.alg, key.method = JWTAlgEdDSA, jwt.SigningMethodEdDSA
+	default:
+		return fmt.Errorf("jwt: jenis kunci %q tidak didukung (%T)", id, public)
+	}
+	k.keys[id] = key
+	return nil
+}
+
+// SetActive memilih kunci yang dipakai untuk menandatangani token baru
+func (k *KeySet) SetActive(id string) error {
+	key, ok := k.keys[id]
+	if !ok {
+		return fmt.Errorf("jwt: kid aktif %q tidak terdaftar", id)
+	}
+	if key.private == nil {
+		return fmt.Errorf("jwt: kid aktif %q tidak punya private key", id)
+	}
+	k.active = key
+	return nil
+}
+
+// SetLegacySecret mengaktifkan verifikasi token HS256 lama. Jika tidak ada kunci aktif,
+// secret ini juga dipakai untuk menandatangani (mode kompatibilitas).
+func (k *KeySet) SetLegacySecret(secret string) {
+	k.legacySecret = []byte(secret)
+}
+
+// CanSign memeriksa bahwa ada kunci yang bisa menandatangani token baru: kunci aktif dengan
+// private key, atau legacy secret dalam mode kompatibilitas
+func (k *KeySet) CanSign() error {
+	if k == nil {
+		return errors.New("jwt: key set belum dimuat")
+	}
+	if k.active == nil && len(k.legacySecret) == 0 {
+		return errors.New("jwt: tidak ada kunci untuk menandatangani")
+	}
+	return nil
+}
+
+// Sign menambahkan iss, aud, iat, nbf dan exp ke claims lalu menandatanganinya dengan kunci aktif
+func (k *KeySet) Sign(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims["iss"] = k.Issuer
+	claims["aud"] = k.Audience
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+
+	if k.active == nil {
+		if len(k.legacySecret) == 0 {
+			return "", errors.New("jwt: tidak ada kunci untuk menandatangani")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.legacySecret)
+	}
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.id
+	return token.SignedString(k.active.private)
+}
+
+// Parse memverifikasi tanda tangan, iss, aud, exp, nbf dan iat, lalu mengembalikan claims
+func (k *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, k.keyFunc,
+		jwt.WithValidMethods([]string{JWTAlgRS256, JWTAlgEdDSA, jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(k.Issuer),
+		jwt.WithAudience(k.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(jwtLeeway),
+	)
+	if err == nil {
+		return claims, nil
+	}
+
+	// Token HS256 lama diterbitkan tanpa iss dan aud; tetap diterima sampai kedaluwarsa
+	if len(k.legacySecret) > 0 && errors.Is(err, jwt.ErrTokenInvalidClaims) {
+		legacy := jwt.MapClaims{}
+		token, legacyErr := jwt.ParseWithClaims(tokenString, legacy, k.keyFunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(jwtLeeway),
+		)
+		if legacyErr == nil && token.Header["kid"] == nil {
+			if _, hasIssuer := legacy["iss"]; !hasIssuer {
+				return legacy, nil
+			}
+		}
+	}
+	return nil, err
+}
+
+func (k *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && len(k.legacySecret) > 0 {
+			return k.legacySecret, nil
+		}
+		return nil, errors.New("jwt: kid tidak ada")
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("jwt: kid %q tidak dikenal", kid)
+	}
+	// Algoritma di header harus sama dengan algoritma kunci agar tidak bisa ditukar
+	if token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("jwt: algoritma %s tidak cocok dengan kid %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// JWK adalah satu public key dalam format JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (OKP)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS adalah isi /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS mengembalikan semua public key yang terdaftar. Secret HS256 tidak pernah dipublikasikan.
+func (k *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.alg}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// ParsePrivateKeyPEM membaca private key RSA (PKCS#1 atau PKCS#8) atau Ed25519 (PKCS#8)
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: PEM private key tidak valid")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: parse private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("jwt: jenis private key tidak didukung (%T)", key)
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyPEM membaca public key PKIX
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: PEM public key tidak valid")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: parse public key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,153 @@
+package helper
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKeySet(t *testing.T) (*KeySet, ed25519.PrivateKey) {
+	t.Helper()
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewKeySet("gobiz", "gobiz-api")
+	if err := ks.AddKey("ed-2024", "", edKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.AddKey("rsa-2025", JWTAlgRS256, rsaKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	return ks, edKey
+}
+
+func TestKeySetRotation(t *testing.T) {
+	ks, _ := newTestKeySet(t)
+	if err := ks.SetActive("ed-2024"); err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := ks.Sign(jwt.MapClaims{"user_id": "u1"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotasi ke kunci baru: token lama tetap valid selama kunci lama masih terdaftar
+	if err := ks.SetActive("rsa-2025"); err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := ks.Sign(jwt.MapClaims{"user_id": "u2"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for token, user := range map[string]string{oldToken: "u1", newToken: "u2"} {
+		claims, err := ks.Parse(token)
+		if err != nil {
+			t.Fatalf("parse %s: %v", user, err)
+		}
+		if claims["user_id"] != user {
+			t.Errorf("user_id = %v, want %s", claims["user_id"], user)
+		}
+	}
+
+	if jwks := ks.JWKS(); len(jwks.Keys) != 2 || jwks.Keys[0].Kty != "OKP" || jwks.Keys[1].Kty != "RSA" {
+		t.Errorf("unexpected JWKS: %+v", jwks)
+	}
+}
+
+func TestKeySetRejectsInvalidTokens(t *testing.T) {
+	ks, edKey := newTestKeySet(t)
+	if err := ks.SetActive("ed-2024"); err != nil {
+		t.Fatal(err)
+	}
+
+	other := NewKeySet("gobiz", "other-service")
+	if err := other.AddKey("ed-2024", "", edKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.SetActive("ed-2024"); err != nil {
+		t.Fatal(err)
+	}
+	wrongAudience, _ := other.Sign(jwt.MapClaims{}, time.Hour)
+	expired, _ := ks.Sign(jwt.MapClaims{}, -time.Hour)
+
+	unknownKid := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"iss": "gobiz", "aud": "gobiz-api", "exp": time.Now().Add(time.Hour).Unix()})
+	unknownKid.Header["kid"] = "missing"
+	unknown, _ := unknownKid.SignedString(edKey)
+
+	// Header alg ditukar ke HS256 dengan public key sebagai secret
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": "gobiz", "aud": "gobiz-api", "exp": time.Now().Add(time.Hour).Unix()})
+	confused.Header["kid"] = "ed-2024"
+	confusedToken, _ := confused.SignedString([]byte(edKey.Public().(ed25519.PublicKey)))
+
+	for name, token := range map[string]string{
+		"wrong audience": wrongAudience,
+		"expired":        expired,
+		"unknown kid":    unknown,
+		"alg confusion":  confusedToken,
+	} {
+		if _, err := ks.Parse(token); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestKeySetLegacyHS256(t *testing.T) {
+	ks := NewKeySet("gobiz", "gobiz-api")
+	ks.SetLegacySecret("s3cret")
+
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "u1",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("s3cret"))
+	if _, err := ks.Parse(legacy); err != nil {
+		t.Errorf("legacy token should still verify: %v", err)
+	}
+
+	// Tanpa kunci asimetris, Sign memakai HS256 dan hasilnya tetap divalidasi penuh
+	signed, err := ks.Sign(jwt.MapClaims{"user_id": "u2"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ks.Parse(signed)
+	if err != nil || claims["iss"] != "gobiz" {
+		t.Errorf("claims = %v, err = %v", claims, err)
+	}
+
+	noExp, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "u1"}).SignedString([]byte("s3cret"))
+	if _, err := ks.Parse(noExp); err == nil {
+		t.Error("token without exp should be rejected")
+	}
+}
+
+func TestKeySetCanSign(t *testing.T) {
+	var missing *KeySet
+	if missing.CanSign() == nil {
+		t.Error("nil key set can sign")
+	}
+
+	ks, _ := newTestKeySet(t)
+	if ks.CanSign() == nil {
+		t.Error("key set without active key or legacy secret can sign")
+	}
+	ks.SetLegacySecret("legacy-secret")
+	if err := ks.CanSign(); err != nil {
+		t.Errorf("legacy secret: %v", err)
+	}
+
+	ks, _ = newTestKeySet(t)
+	if err := ks.SetActive("ed-2024"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.CanSign(); err != nil {
+		t.Errorf("active key: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSMTPMailerFormat(t *testing.T) {
+	m := &SMTPMailer{From: "GoBiz <no-reply@gobiz.local>"}
+	raw := string(m.format(Message{To: "user@example.com", Subject: "Verify your email", Body: "line1\nline2"}))
+
+	for _, want := range []string{
+		"From: GoBiz <no-reply@gobiz.local>\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Verify your email\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n\r\nline1\r\nline2",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("message missing %q:\n%s", want, raw)
+		}
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	m := &SMTPMailer{Host: "localhost", Port: 25, From: "no-reply@gobiz.local"}
+	err := m.Send(context.Background(), Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "x"})
+	if err == nil {
+		t.Fatal("expected error for recipient with newline")
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	var m MemoryMailer
+	if err := m.Send(context.Background(), Message{To: "a@example.com", Subject: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := m.Messages()
+	if len(msgs) != 1 || msgs[0].To != "a@example.com" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+}
+
+func TestSMTPMailerStopsAtContextDeadline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// Server menerima koneksi tetapi tidak pernah mengirim greeting
+	closed := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 1)
+		conn.Read(buf)
+		conn.Close()
+		close(closed)
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	m := &SMTPMailer{Host: "127.0.0.1", Port: addr.Port, From: "no-reply@gobiz.local"}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := m.Send(ctx, Message{To: "user@example.com", Subject: "Hi", Body: "x"}); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Send took %v after the deadline", elapsed)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("connection still open after Send returned")
+	}
+}
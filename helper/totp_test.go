@@ -0,0 +1,60 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vektor uji SHA1 dari RFC 6238 Appendix B
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, want := range vectors {
+		if got := hotp(key, uint64(TOTPStep(time.Unix(unix, 0))), 8); got != want {
+			t.Errorf("T=%d: got %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := ValidateTOTP(secret, code, now.Add(TOTPPeriod))
+	if !ok || step != TOTPStep(now) {
+		t.Errorf("code from previous step should be accepted, got step=%d ok=%v", step, ok)
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(3*TOTPPeriod)); ok {
+		t.Error("code outside skew window should be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Error("short code should be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("GoBiz", "admin@gobiz.id", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/GoBiz:admin@gobiz.id?") {
+		t.Errorf("unexpected label: %s", uri)
+	}
+	for _, want := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=GoBiz", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("uri missing %s: %s", want, uri)
+		}
+	}
+}
@@ -1,23 +1,142 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ChekoutGobiz/BackendChekout/config"
+	controllers "github.com/ChekoutGobiz/BackendChekout/controller"
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	"github.com/ChekoutGobiz/BackendChekout/middleware"
 	"github.com/ChekoutGobiz/BackendChekout/url"
 	"github.com/gofiber/fiber/v2"
 )
 
 func main() {
+	// Memuat konfigurasi dari file, environment dan flag
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("Gagal memuat konfigurasi", err)
+	}
+	level, _ := cfg.SlogLevel()
+	slog.SetDefault(helper.NewLogger(os.Stdout, level))
+
+	// Tracing dipasang sebelum koneksi MongoDB agar operasi database ikut tercatat
+	tp, err := config.SetupTracing(context.Background(), cfg)
+	if err != nil {
+		fatal("Gagal menyiapkan tracing", err)
+	}
+	config.OnShutdown("tracing", tp.Shutdown)
+
 	// Membuat instance aplikasi Fiber dengan konfigurasi yang telah disediakan
-	app := fiber.New(config.GoBiz)
+	fiberConfig := cfg.Fiber()
+	fiberConfig.ErrorHandler = middleware.ErrorHandler
+	app := fiber.New(fiberConfig)
+
+	// Dengan Prefork, proses master hanya mem-fork child dan tidak pernah melayani request,
+	// jadi hanya child (atau proses tunggal tanpa Prefork) yang membuka koneksi MongoDB
+	serving := !cfg.Prefork || fiber.IsChild()
+	if serving {
+		client, err := config.ConnectDB(cfg)
+		if err != nil {
+			fatal("Gagal koneksi ke MongoDB", err)
+		}
+		config.OnShutdown("mongodb", client.Disconnect)
+		db := client.Database(cfg.DBName)
+		keys, err := config.NewKeySet(cfg)
+		if err != nil {
+			fatal("Gagal memuat kunci JWT", err)
+		}
+		if err := controllers.Init(db, cfg, config.NewMailer(cfg), keys, config.NewBlobStore(cfg)); err != nil {
+			fatal("Gagal menyiapkan controller", err)
+		}
+
+		// Tracing, request ID, access log dan metrik untuk setiap request
+		app.Use(middleware.Tracing)
+		app.Use(middleware.RequestID)
+		app.Use(middleware.AccessLog)
+		app.Use(middleware.Metrics)
+
+		// Menambahkan middleware CORS dengan pengaturan yang ada di config
+		app.Use(middleware.CORS(cfg.CORS))
+
+		// Setup semua routes
+		if err := url.SetupRoutes(app, db, cfg, keys); err != nil {
+			fatal("Gagal menyiapkan routes", err)
+		}
+
+		// Notifikasi penurunan harga wishlist berjalan di latar belakang sampai shutdown
+		notifierCtx, stopNotifier := context.WithCancel(context.Background())
+		go controllers.RunPriceDropNotifier(notifierCtx, cfg.Wishlist.PriceDropInterval.Std())
+		config.OnShutdown("price-drop-notifier", func(context.Context) error {
+			stopNotifier()
+			return nil
+		})
+
+		// Diskon terjadwal diterapkan dan dikembalikan di latar belakang sampai shutdown
+		schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+		go controllers.RunPriceScheduler(schedulerCtx, cfg.Pricing.SaleSchedulerInterval.Std())
+		config.OnShutdown("price-scheduler", func(context.Context) error {
+			stopScheduler()
+			return nil
+		})
+		config.OnShutdown("catalog-imports", controllers.StopCatalogImports)
+	}
 
-	// Menambahkan middleware CORS dengan pengaturan yang ada di config
-	app.Use(config.Cors)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Setup semua routes
-	url.SetupRoutes(app)
+	var children childProcesses
+	if !serving {
+		app.Hooks().OnFork(children.add)
+	}
+
+	// Memulai aplikasi pada alamat yang telah disetting
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(cfg.Address)
+	}()
+
+	select {
+	case err := <-listenErr:
+		if err != nil {
+			fatal("Server berhenti", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	slog.Info("Sinyal berhenti diterima, memulai graceful shutdown")
+	timeout := cfg.ShutdownTimeout.Std()
+	if !serving {
+		children.shutdown(timeout)
+		return
+	}
+
+	// Readiness gagal lebih dulu agar load balancer berhenti mengirim request baru
+	config.BeginShutdown()
+	time.Sleep(cfg.ShutdownDelay.Std())
+
+	// Berhenti menerima koneksi baru dan tunggu request yang sedang berjalan
+	if err := app.ShutdownWithTimeout(timeout); err != nil {
+		slog.Error("Gagal menyelesaikan request yang berjalan", "error", err)
+	}
+
+	hookCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	config.Shutdown(hookCtx)
+	cancel()
+	slog.Info("Graceful shutdown selesai")
+
+	if fiber.IsChild() {
+		notifyMasterAndWait()
+	}
+}
 
-	// Memulai aplikasi pada port yang telah disetting
-	log.Fatal(app.Listen(config.IPPort))
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrik HTTP (RED) per route template, contoh /api/cart/:product_id
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Jumlah request HTTP per route template, method dan status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latensi request HTTP per route template, method dan status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	HTTPInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Jumlah request HTTP yang sedang diproses.",
+	})
+)
+
+// Metrik bisnis
+var (
+	Registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gobiz_registrations_total",
+		Help: "Jumlah registrasi pengguna yang berhasil.",
+	})
+
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gobiz_logins_total",
+		Help: "Jumlah percobaan login berdasarkan hasil (success, failure).",
+	}, []string{"result"})
+
+	CartAdds = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gobiz_cart_adds_total",
+		Help: "Jumlah item yang berhasil ditambahkan ke keranjang.",
+	})
+
+	// Checkouts dan Revenue belum dinaikkan di mana pun karena service ini belum punya alur
+	// checkout; keduanya sudah diekspor (bernilai 0) agar dashboard dan alert bisa disiapkan.
+	// Alur checkout menaikkan keduanya bersamaan untuk setiap pesanan yang berhasil dibuat.
+	Checkouts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gobiz_checkouts_total",
+		Help: "Jumlah checkout yang berhasil.",
+	})
+
+	Revenue = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gobiz_revenue_rupiah_total",
+		Help: "Total nilai checkout yang berhasil dalam rupiah.",
+	})
+)
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var (
+	mongoCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mongodb_command_duration_seconds",
+		Help:    "Latensi perintah MongoDB per nama perintah dan hasil.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "status"})
+
+	mongoPoolEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongodb_pool_events_total",
+		Help: "Jumlah event connection pool MongoDB per tipe.",
+	}, []string{"type"})
+
+	mongoConnectionsOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_pool_connections_open",
+		Help: "Jumlah koneksi MongoDB yang terbuka.",
+	})
+
+	mongoConnectionsInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mongodb_pool_connections_in_use",
+		Help: "Jumlah koneksi MongoDB yang sedang dipakai.",
+	})
+)
+
+// CommandMonitor mencatat latensi setiap perintah MongoDB
+func CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			mongoCommandDuration.WithLabelValues(e.CommandName, "success").Observe(seconds(e.Duration))
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			mongoCommandDuration.WithLabelValues(e.CommandName, "failure").Observe(seconds(e.Duration))
+		},
+	}
+}
+
+// PoolMonitor mencatat statistik connection pool MongoDB
+func PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			mongoPoolEvents.WithLabelValues(e.Type).Inc()
+			switch e.Type {
+			case event.ConnectionCreated:
+				mongoConnectionsOpen.Inc()
+			case event.ConnectionClosed:
+				mongoConnectionsOpen.Dec()
+			case event.GetSucceeded:
+				mongoConnectionsInUse.Inc()
+			case event.ConnectionReturned:
+				mongoConnectionsInUse.Dec()
+			}
+		},
+	}
+}
+
+func seconds(d time.Duration) float64 {
+	return d.Seconds()
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LimitBody membaca body request ke memori dengan batas limit byte dan menolak body yang lebih
+// besar dengan 413. Dipakai bersama fiber.Config.StreamRequestBody: dalam mode itu fasthttp tidak
+// menolak body besar, sehingga batasnya diterapkan di sini sebelum handler memanggil c.Body().
+//
+// Route di streamed (dalam bentuk "METHOD /path") dilewati. Handler route itu membaca body sebagai
+// stream lewat c.Context().RequestBodyStream() dan wajib menerapkan batasnya sendiri.
+//
+// fasthttp tidak membuang body yang tidak dibaca sampai habis; sisa body itu akan dibaca sebagai
+// request berikutnya di koneksi yang sama. Karena itu koneksi ditutup setelah respons setiap kali
+// body mungkin tersisa: saat body ditolak dan untuk semua request ke route streamed.
+func LimitBody(limit int, streamed ...string) fiber.Handler {
+	skip := make(map[string]bool, len(streamed))
+	for _, route := range streamed {
+		skip[route] = true
+	}
+	return func(c *fiber.Ctx) error {
+		if skip[c.Method()+" "+c.Path()] {
+			c.Context().SetConnectionClose()
+			return c.Next()
+		}
+		req := c.Request()
+		if req.Header.ContentLength() > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		if !req.IsBodyStream() {
+			return c.Next()
+		}
+		// Body chunked tidak punya Content-Length, jadi ukurannya diperiksa saat dibaca
+		body, err := io.ReadAll(io.LimitReader(c.Context().RequestBodyStream(), int64(limit)+1))
+		if err != nil {
+			c.Context().SetConnectionClose()
+			return fiber.NewError(fiber.StatusBadRequest, "Failed to read request body")
+		}
+		if len(body) > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		req.SetBodyRaw(body)
+		return c.Next()
+	}
+}
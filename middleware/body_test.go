@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLimitBody(t *testing.T) {
+	app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true, BodyLimit: 16})
+	app.Use(LimitBody(16, "POST /import"))
+	echo := func(c *fiber.Ctx) error { return c.Send(c.Body()) }
+	app.Post("/login", echo)
+	app.Post("/import", func(c *fiber.Ctx) error {
+		n, err := io.Copy(io.Discard, c.Context().RequestBodyStream())
+		if err != nil {
+			return err
+		}
+		return c.JSON(n)
+	})
+
+	tests := []struct {
+		path    string
+		body    string
+		chunked bool
+		status  int
+		want    string
+	}{
+		{"/login", `{"email":"a"}`, false, 200, `{"email":"a"}`},
+		{"/login", strings.Repeat("x", 17), false, fiber.StatusRequestEntityTooLarge, ""},
+		{"/login", `{"a":1}`, true, 200, `{"a":1}`},
+		{"/login", strings.Repeat("x", 64<<10), true, fiber.StatusRequestEntityTooLarge, ""},
+		{"/import", strings.Repeat("x", 64<<10), false, 200, "65536"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		if tt.chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.status || (tt.want != "" && string(got) != tt.want) {
+			t.Errorf("%s %d bytes (chunked %v): %d %q", tt.path, len(tt.body), tt.chunked, resp.StatusCode, got)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ChekoutGobiz/BackendChekout/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CORS menerapkan policy CORS dari konfigurasi, termasuk override per route
+func CORS(cfg config.CORSConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		origin := c.Get(fiber.HeaderOrigin)
+		if origin == "" {
+			return c.Next()
+		}
+		c.Vary(fiber.HeaderOrigin)
+
+		policy := cfg.PolicyFor(c.Path())
+		allowed := originAllowed(policy.AllowOrigins, origin)
+		preflight := c.Method() == fiber.MethodOptions && c.Get(fiber.HeaderAccessControlRequestMethod) != ""
+
+		if !preflight {
+			if allowed {
+				setAllowOrigin(c, policy, origin)
+				if len(policy.ExposeHeaders) > 0 {
+					c.Set(fiber.HeaderAccessControlExposeHeaders, strings.Join(policy.ExposeHeaders, ","))
+				}
+			}
+			return c.Next()
+		}
+
+		// Preflight yang ditolak dijawab tanpa header CORS sehingga browser memblokirnya
+		if !allowed {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+
+		c.Vary(fiber.HeaderAccessControlRequestMethod, fiber.HeaderAccessControlRequestHeaders)
+		setAllowOrigin(c, policy, origin)
+		c.Set(fiber.HeaderAccessControlAllowMethods, strings.Join(policy.AllowMethods, ","))
+		if len(policy.AllowHeaders) > 0 {
+			c.Set(fiber.HeaderAccessControlAllowHeaders, strings.Join(policy.AllowHeaders, ","))
+		} else if requested := c.Get(fiber.HeaderAccessControlRequestHeaders); requested != "" {
+			c.Set(fiber.HeaderAccessControlAllowHeaders, requested)
+		}
+		if policy.MaxAge > 0 {
+			c.Set(fiber.HeaderAccessControlMaxAge, strconv.Itoa(policy.MaxAge))
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// setAllowOrigin mengembalikan origin yang cocok dengan allowlist. Origin yang hanya cocok
+// karena "*" dijawab dengan "*" tanpa credentials, sehingga situs sembarang tidak pernah bisa
+// mengirim request dengan cookie walaupun konfigurasi lolos tanpa validasi.
+func setAllowOrigin(c *fiber.Ctx, policy config.CORSPolicy, origin string) {
+	if !originAllowed(specificOrigins(policy.AllowOrigins), origin) {
+		c.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+		return
+	}
+	c.Set(fiber.HeaderAccessControlAllowOrigin, origin)
+	if policy.AllowCredentials {
+		c.Set(fiber.HeaderAccessControlAllowCredentials, "true")
+	}
+}
+
+// specificOrigins mengembalikan allowlist tanpa "*"
+func specificOrigins(allowlist []string) []string {
+	specific := make([]string, 0, len(allowlist))
+	for _, pattern := range allowlist {
+		if pattern != "*" {
+			specific = append(specific, pattern)
+		}
+	}
+	return specific
+}
+
+// originAllowed mencocokkan origin dengan allowlist; "https://*.gobiz.id" cocok dengan
+// semua subdomain gobiz.id tetapi tidak dengan gobiz.id itu sendiri
+func originAllowed(allowlist []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowlist {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		scheme, host, ok := strings.Cut(pattern, "://*.")
+		if !ok {
+			continue
+		}
+		prefix := scheme + "://"
+		if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, "."+host) && len(origin) > len(prefix)+len(host)+1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ChekoutGobiz/BackendChekout/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func corsApp() *fiber.App {
+	cfg := config.DefaultCORS()
+	cfg.AllowOrigins = []string{"https://satsetin.github.io", "https://*.gobiz.id"}
+	cfg.ExposeHeaders = []string{"X-Request-Id"}
+	cfg.Routes = map[string]config.CORSPolicy{
+		"/api/public": {
+			AllowOrigins: []string{"*"},
+			AllowMethods: []string{"GET"},
+			MaxAge:       60,
+		},
+	}
+
+	app := fiber.New()
+	app.Use(CORS(cfg))
+	app.Get("/api/products", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/api/public/regions", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	return app
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	for _, origin := range []string{"https://satsetin.github.io", "https://shop.gobiz.id"} {
+		req := httptest.NewRequest("GET", "/api/products", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := corsApp().Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("%s: Allow-Origin = %q", origin, got)
+		}
+		if resp.Header.Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("%s: credentials not allowed", origin)
+		}
+		if resp.Header.Get("Access-Control-Expose-Headers") != "X-Request-Id" {
+			t.Errorf("%s: expose headers missing", origin)
+		}
+	}
+}
+
+func TestCORSDeniedOrigin(t *testing.T) {
+	for _, origin := range []string{"https://evil.example", "https://gobiz.id", "http://shop.gobiz.id"} {
+		req := httptest.NewRequest("GET", "/api/products", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := corsApp().Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s: unexpected Allow-Origin %q", origin, got)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("%s: request should still reach handler, got %d", origin, resp.StatusCode)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	req := httptest.NewRequest("OPTIONS", "/api/products", nil)
+	req.Header.Set("Origin", "https://satsetin.github.io")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	resp, err := corsApp().Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://satsetin.github.io",
+		"Access-Control-Allow-Methods": "GET,POST,PUT,PATCH,DELETE",
+		"Access-Control-Allow-Headers": "Content-Type,Authorization,X-Cart-Token,If-Match",
+		"Access-Control-Max-Age":       "3600",
+	}
+	for header, value := range want {
+		if got := resp.Header.Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/api/products", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	resp, err = corsApp().Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" || resp.Header.Get("Access-Control-Allow-Methods") != "" {
+		t.Error("denied preflight must not carry CORS headers")
+	}
+}
+
+func TestCORSRouteOverride(t *testing.T) {
+	req := httptest.NewRequest("OPTIONS", "/api/public/regions", nil)
+	req.Header.Set("Origin", "https://anyone.example")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	resp, err := corsApp().Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Methods"); got != "GET" {
+		t.Errorf("Allow-Methods = %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Headers"); got != "X-Custom" {
+		t.Errorf("Allow-Headers = %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Max-Age"); got != "60" {
+		t.Errorf("Max-Age = %q", got)
+	}
+}
+
+func TestCORSWildcardNeverAllowsCredentials(t *testing.T) {
+	cfg := config.DefaultCORS()
+	cfg.AllowOrigins = []string{"*", "https://satsetin.github.io"}
+	app := fiber.New()
+	app.Use(CORS(cfg))
+	app.Get("/api/cart", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	want := map[string][2]string{
+		"https://evil.example":       {"*", ""},
+		"https://satsetin.github.io": {"https://satsetin.github.io", "true"},
+	}
+	for origin, headers := range want {
+		req := httptest.NewRequest("GET", "/api/cart", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != headers[0] {
+			t.Errorf("%s: Allow-Origin = %q", origin, got)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Credentials"); got != headers[1] {
+			t.Errorf("%s: Allow-Credentials = %q", origin, got)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/metrics"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Metrics mencatat jumlah request, status dan latensi per route template.
+// Request yang tidak cocok dengan route mana pun dicatat sebagai "unmatched"
+// agar path acak tidak menambah kardinalitas label.
+func Metrics(c *fiber.Ctx) error {
+	start := time.Now()
+	self := c.Route()
+	metrics.HTTPInFlight.Inc()
+	defer metrics.HTTPInFlight.Dec()
+
+	err := c.Next()
+
+	status := responseStatus(c, err)
+
+	route := "unmatched"
+	if r := c.Route(); r != self {
+		route = r.Path
+	}
+	// Method dari Fiber memakai buffer fasthttp yang dipakai ulang, jadi harus disalin
+	labels := []string{utils.CopyString(c.Method()), route, strconv.Itoa(status)}
+	metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+	metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	return err
+}
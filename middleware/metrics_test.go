@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ChekoutGobiz/BackendChekout/metrics"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsUsesRouteTemplate(t *testing.T) {
+	app := fiber.New()
+	app.Use(Metrics)
+	app.Delete("/api/cart/:product_id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	for _, id := range []string{"a1", "b2", "c3"} {
+		if _, err := app.Test(httptest.NewRequest("DELETE", "/api/cart/"+id, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := app.Test(httptest.NewRequest("GET", "/random/path", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("DELETE", "/api/cart/:product_id", "204")); got != 3 {
+		t.Errorf("templated route counter = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("unmatched counter = %v, want 1", got)
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	models "github.com/ChekoutGobiz/BackendChekout/model"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HeaderAPIKey adalah header untuk autentikasi dengan API key
+const HeaderAPIKey = "X-API-Key"
+
+// LocalPrincipal adalah key c.Locals untuk *Principal
+const LocalPrincipal = "principal"
+
+// TokenAPIKey adalah nilai LocalTokenType untuk request yang memakai API key
+const TokenAPIKey = "api_key"
+
+// Principal adalah identitas pemanggil, baik dari JWT maupun API key
+type Principal struct {
+	UserID primitive.ObjectID
+	Email  string
+	Role   string
+	// APIKeyID tidak nol jika request memakai API key
+	APIKeyID primitive.ObjectID
+	// Scopes hanya berlaku untuk API key; sesi JWT punya akses penuh sesuai role
+	Scopes []string
+}
+
+// IsAPIKey melaporkan apakah principal berasal dari API key
+func (p *Principal) IsAPIKey() bool {
+	return !p.APIKeyID.IsZero()
+}
+
+// HasScope melaporkan apakah principal boleh melakukan aksi dengan scope tertentu
+func (p *Principal) HasScope(scope string) bool {
+	if !p.IsAPIKey() {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFrom mengambil principal yang diisi VerifyJWT atau Authenticate
+func PrincipalFrom(c *fiber.Ctx) *Principal {
+	p, _ := c.Locals(LocalPrincipal).(*Principal)
+	return p
+}
+
+// Authenticate menerima header X-API-Key atau, jika tidak ada, meneruskan ke verifyJWT.
+// Keduanya menghasilkan Principal yang sama. Hanya pasang di rute yang boleh diakses API key
+// dan lindungi dengan RequireScope.
+func Authenticate(verifyJWT fiber.Handler, users, apiKeys *mongo.Collection) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		raw := c.Get(HeaderAPIKey)
+		if raw == "" {
+			return verifyJWT(c)
+		}
+
+		now := time.Now()
+		key, err := models.FindActiveAPIKey(c.UserContext(), apiKeys, raw, now)
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid API key"})
+		}
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error finding API key", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify API key"})
+		}
+
+		// Pemilik harus masih ada dan masih merchant/admin
+		var owner models.User
+		err = users.FindOne(c.UserContext(), bson.M{"_id": key.OwnerID},
+			options.FindOne().SetProjection(bson.M{"email": 1, "role": 1}),
+		).Decode(&owner)
+		if err == mongo.ErrNoDocuments || (err == nil && owner.Role != models.RoleMerchant && owner.Role != models.RoleAdmin) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid API key"})
+		}
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error finding API key owner", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify API key"})
+		}
+
+		if err := models.TouchAPIKey(c.UserContext(), apiKeys, key.ID, now); err != nil {
+			slog.WarnContext(c.UserContext(), "Error updating API key last use", "error", err)
+		}
+
+		c.Locals(LocalUserID, owner.ID.Hex())
+		c.Locals(LocalEmail, owner.Email)
+		c.Locals(LocalRole, owner.Role)
+		c.Locals(LocalTokenType, TokenAPIKey)
+		c.Locals(LocalPrincipal, &Principal{
+			UserID:   owner.ID,
+			Email:    owner.Email,
+			Role:     owner.Role,
+			APIKeyID: key.ID,
+			Scopes:   key.Scopes,
+		})
+		return c.Next()
+	}
+}
+
+// RequireScope menolak API key yang tidak punya scope. Sesi JWT selalu diteruskan.
+// Harus dipasang setelah Authenticate.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := PrincipalFrom(c)
+		if p == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		if !p.HasScope(scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API key is missing scope " + scope})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LimiterStore menyimpan riwayat hit untuk sliding window rate limiter
+type LimiterStore interface {
+	// Hit mencatat satu hit pada waktu now, membuang hit yang lebih tua dari window dan
+	// menyisakan paling banyak keep hit terbaru, lalu mengembalikan hit yang tersisa
+	// (termasuk hit ini) terurut dari yang terlama
+	Hit(ctx context.Context, key string, now time.Time, window time.Duration, keep int) ([]time.Time, error)
+}
+
+// Limiter membatasi Limit hit per key dalam sliding window sepanjang Window
+type Limiter struct {
+	Store  LimiterStore
+	Name   string
+	Limit  int
+	Window time.Duration
+
+	now func() time.Time
+}
+
+// LimitResult adalah hasil pemeriksaan rate limit untuk satu hit
+type LimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset adalah waktu sampai kuota bertambah lagi
+	Reset time.Duration
+}
+
+// Allow mencatat hit untuk key dan melaporkan apakah masih di dalam batas
+func (l *Limiter) Allow(ctx context.Context, key string) (LimitResult, error) {
+	now := time.Now()
+	if l.now != nil {
+		now = l.now()
+	}
+	// Limit+1 hit terbaru cukup untuk mengetahui apakah batas terlampaui dan kapan kuota kembali,
+	// sehingga riwayat tidak terus bertambah selama banjir request yang ditolak
+	hits, err := l.Store.Hit(ctx, l.Name+":"+key, now, l.Window, l.Limit+1)
+	if err != nil {
+		return LimitResult{Allowed: true, Limit: l.Limit, Remaining: l.Limit}, err
+	}
+
+	result := LimitResult{
+		Allowed:   len(hits) <= l.Limit,
+		Limit:     l.Limit,
+		Remaining: l.Limit - len(hits),
+	}
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	// Kuota bertambah saat hit yang membuat batas terlampaui keluar dari window
+	if len(hits) >= l.Limit {
+		oldest := hits[len(hits)-l.Limit]
+		result.Reset = oldest.Add(l.Window).Sub(now)
+	}
+	return result, nil
+}
+
+// RateLimit menolak request dengan 429 setelah batas terlampaui dan menambahkan header
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset serta Retry-After.
+// Request dengan key kosong tidak dibatasi.
+func RateLimit(l *Limiter, key func(*fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		k := key(c)
+		if k == "" {
+			return c.Next()
+		}
+
+		result, err := l.Allow(c.UserContext(), k)
+		if err != nil {
+			// Gagal terbuka: gangguan store tidak boleh membuat login tidak bisa dipakai
+			slog.ErrorContext(c.UserContext(), "Rate limiter store error", "limiter", l.Name, "error", err)
+			return c.Next()
+		}
+
+		SetRateLimitHeaders(c, result)
+		if !result.Allowed {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many requests, please try again later",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// SetRateLimitHeaders menulis header rate limit standar, termasuk Retry-After jika ditolak
+func SetRateLimitHeaders(c *fiber.Ctx, result LimitResult) {
+	reset := ceilSeconds(result.Reset)
+	c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(reset))
+	if !result.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(reset))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// KeyByIP membatasi per alamat IP client
+func KeyByIP(c *fiber.Ctx) string {
+	return c.IP()
+}
+
+// KeyByEmail membatasi per akun berdasarkan field email di body JSON
+func KeyByEmail(c *fiber.Ctx) string {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(body.Email))
+}
+
+// MemoryLimiterStore menyimpan hit di memori proses. Dengan Prefork setiap child punya
+// hitungan sendiri; gunakan MongoLimiterStore agar batas berlaku untuk semua proses.
+type MemoryLimiterStore struct {
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryLimiterStore() *MemoryLimiterStore {
+	return &MemoryLimiterStore{hits: make(map[string][]time.Time)}
+}
+
+func (s *MemoryLimiterStore) Hit(_ context.Context, key string, now time.Time, window time.Duration, keep int) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := now.Add(-window)
+	hits := append(prune(s.hits[key], cutoff), now)
+	if len(hits) > keep {
+		hits = hits[len(hits)-keep:]
+	}
+	s.hits[key] = hits
+
+	// Bersihkan key yang sudah tidak aktif sesekali agar memori tidak terus bertambah
+	if now.Sub(s.lastSweep) > window {
+		for k, v := range s.hits {
+			if len(prune(v, cutoff)) == 0 {
+				delete(s.hits, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	return append([]time.Time(nil), hits...), nil
+}
+
+func prune(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
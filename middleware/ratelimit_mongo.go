@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoLimiterStore menyimpan hit di MongoDB sehingga batas berlaku untuk semua proses.
+// Dokumen yang tidak aktif dihapus oleh TTL index pada expires_at.
+type MongoLimiterStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoLimiterStore membuat store dan memastikan TTL index tersedia
+func NewMongoLimiterStore(ctx context.Context, collection *mongo.Collection) (*MongoLimiterStore, error) {
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoLimiterStore{collection: collection}, nil
+}
+
+func (s *MongoLimiterStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration, keep int) ([]time.Time, error) {
+	now = now.Truncate(time.Millisecond)
+	cutoff := now.Add(-window)
+
+	// Update pipeline membuang hit lama, menambahkan hit baru dan menyisakan keep hit terbaru
+	// secara atomik, sehingga dokumen tidak mendekati batas 16 MB selama banjir request
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "hits", Value: bson.D{{Key: "$slice", Value: bson.A{
+				bson.D{{Key: "$concatArrays", Value: bson.A{
+					bson.D{{Key: "$filter", Value: bson.D{
+						{Key: "input", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$hits", bson.A{}}}}},
+						{Key: "cond", Value: bson.D{{Key: "$gt", Value: bson.A{"$$this", cutoff}}}},
+					}}},
+					bson.A{now},
+				}}},
+				-keep,
+			}}}},
+			{Key: "expires_at", Value: now.Add(window)},
+		}}},
+	}
+
+	var doc struct {
+		Hits []time.Time `bson:"hits"`
+	}
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Hits, nil
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitSlidingWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := &Limiter{
+		Store:  NewMemoryLimiterStore(),
+		Name:   "login_account",
+		Limit:  2,
+		Window: time.Minute,
+		now:    func() time.Time { return now },
+	}
+
+	app := fiber.New()
+	app.Post("/login", RateLimit(limiter, KeyByEmail), func(c *fiber.Ctx) error { return c.SendString("ok") })
+	send := func(email string) (int, map[string]string) {
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers := map[string]string{}
+		for _, h := range []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"} {
+			headers[h] = resp.Header.Get(h)
+		}
+		return resp.StatusCode, headers
+	}
+
+	if status, h := send("a@example.com"); status != 200 || h["RateLimit-Remaining"] != "1" || h["RateLimit-Limit"] != "2" {
+		t.Fatalf("first request: %d %v", status, h)
+	}
+	now = now.Add(30 * time.Second)
+	if status, h := send("A@example.com"); status != 200 || h["RateLimit-Remaining"] != "0" {
+		t.Fatalf("second request: %d %v", status, h)
+	}
+	now = now.Add(10 * time.Second)
+	status, h := send("a@example.com")
+	if status != fiber.StatusTooManyRequests {
+		t.Fatalf("third request should be limited, got %d", status)
+	}
+	// Hit yang ditolak ikut dihitung, jadi kuota kembali setelah hit kedua (detik ke-30) keluar dari window
+	if h["Retry-After"] != "50" || h["RateLimit-Reset"] != "50" {
+		t.Fatalf("unexpected reset headers %v", h)
+	}
+
+	// Akun lain tidak terpengaruh
+	if status, _ := send("b@example.com"); status != 200 {
+		t.Fatalf("other account limited: %d", status)
+	}
+
+	// Setelah window bergeser, hit lama tidak dihitung lagi
+	now = now.Add(2 * time.Minute)
+	if status, _ := send("a@example.com"); status != 200 {
+		t.Fatalf("window did not slide: %d", status)
+	}
+}
+
+func TestMemoryLimiterStoreKeepsNewestHits(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := &Limiter{
+		Store:  NewMemoryLimiterStore(),
+		Name:   "login_ip",
+		Limit:  3,
+		Window: time.Minute,
+		now:    func() time.Time { return now },
+	}
+	var result LimitResult
+	for i := 0; i < 1000; i++ {
+		now = now.Add(10 * time.Millisecond)
+		var err error
+		if result, err = limiter.Allow(context.Background(), "203.0.113.7"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if result.Allowed {
+		t.Fatal("flood should stay limited")
+	}
+	if n := len(limiter.Store.(*MemoryLimiterStore).hits["login_ip:203.0.113.7"]); n != 4 {
+		t.Errorf("stored %d hits, want Limit+1", n)
+	}
+	// Kuota kembali saat hit ke-Limit terbaru keluar dari window
+	if result.Reset != time.Minute-20*time.Millisecond {
+		t.Errorf("Reset = %v", result.Reset)
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// HeaderRequestID adalah header untuk meneruskan request ID antar layanan
+const HeaderRequestID = "X-Request-Id"
+
+// LocalRequestID adalah key c.Locals untuk request ID
+const LocalRequestID = "request_id"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID memakai X-Request-Id dari client jika valid, atau membuat yang baru.
+// ID disimpan di c.Locals, header response dan UserContext sehingga ikut tercatat di log.
+func RequestID(c *fiber.Ctx) error {
+	id := c.Get(HeaderRequestID)
+	if !validRequestID.MatchString(id) {
+		id = newRequestID()
+	} else {
+		id = utils.CopyString(id)
+	}
+
+	c.Locals(LocalRequestID, id)
+	c.Set(HeaderRequestID, id)
+	c.SetUserContext(helper.ContextWithRequestID(c.UserContext(), id))
+	return c.Next()
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
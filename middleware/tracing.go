@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/ChekoutGobiz/BackendChekout/middleware"
+
+// Tracing membuat server span untuk setiap request, melanjutkan trace dari header
+// traceparent jika ada. Span disimpan di UserContext sehingga operasi MongoDB dan
+// panggilan keluar menjadi child span.
+func Tracing(c *fiber.Ctx) error {
+	header := make(propagation.HeaderCarrier)
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		header.Set(string(key), string(value))
+	})
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), header)
+
+	method := utils.CopyString(c.Method())
+	ctx, span := otel.Tracer(tracerName).Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLPath(utils.CopyString(c.Path())),
+			semconv.ClientAddress(c.IP()),
+		),
+	)
+	defer span.End()
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+
+	status := responseStatus(c, err)
+	route := c.Route().Path
+	span.SetName(method + " " + route)
+	span.SetAttributes(semconv.HTTPRoute(route))
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if err != nil {
+		span.RecordError(err)
+	}
+	if status >= fiber.StatusInternalServerError {
+		span.SetStatus(codes.Error, utils.StatusMessage(status))
+	}
+
+	if err == nil && status >= fiber.StatusBadRequest {
+		addTraceID(c, span.SpanContext())
+	}
+	return err
+}
+
+// ErrorHandler menulis error yang dikembalikan handler sebagai JSON lengkap dengan trace ID
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	message := utils.StatusMessage(status)
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status, message = fiberErr.Code, fiberErr.Message
+	}
+
+	body := fiber.Map{"error": message}
+	if sc := trace.SpanContextFromContext(c.UserContext()); sc.HasTraceID() {
+		body["trace_id"] = sc.TraceID().String()
+	}
+	return c.Status(status).JSON(body)
+}
+
+// addTraceID menambahkan trace_id ke body response error berbentuk objek JSON
+func addTraceID(c *fiber.Ctx, sc trace.SpanContext) {
+	if !sc.HasTraceID() {
+		return
+	}
+	if !strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+		return
+	}
+
+	var body map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(c.Response().Body()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil || body == nil {
+		return
+	}
+	if _, exists := body["trace_id"]; exists {
+		return
+	}
+	body["trace_id"] = sc.TraceID().String()
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	c.Response().SetBodyRaw(encoded)
+}
@@ -1,49 +1,161 @@
 package middleware
 
 import (
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
+	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// VerifyJWT memverifikasi token JWT yang diterima di header Authorization
-func VerifyJWT(c *fiber.Ctx) error {
-	// Ambil token dari header Authorization
-	tokenString := c.Get("Authorization")
-	log.Println("Received token:", tokenString)
-
-	// Token harus diawali dengan "Bearer "
-	if len(tokenString) < 7 || tokenString[:7] != "Bearer " {
-		log.Println("Invalid token format")
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token format",
-		})
+// Key c.Locals yang diisi VerifyJWT dari claims token
+const (
+	LocalUserID = "user_id"
+	LocalEmail  = "email"
+	LocalRole   = "role"
+	// LocalTokenType berisi jenis token (TokenAccess, TokenMFAEnroll, ...)
+	LocalTokenType = "token_type"
+)
+
+// Jenis token (claim "typ"). Token tanpa typ dianggap TokenAccess.
+const (
+	// TokenAccess adalah token sesi biasa
+	TokenAccess = "access"
+	// TokenMFAPending diterbitkan setelah password benar dan ditukar dengan TokenAccess memakai kode 2FA
+	TokenMFAPending = "mfa_pending"
+	// TokenMFAEnroll hanya boleh dipakai untuk mendaftarkan 2FA ketika role mewajibkannya
+	TokenMFAEnroll = "mfa_enroll"
+	// TokenGuestCart mengidentifikasi keranjang tamu, tidak pernah diterima sebagai login
+	TokenGuestCart = "guest_cart"
+)
+
+// AccessTokenTTL adalah masa berlaku TokenAccess, token terpanjang yang bisa dicabut lewat logout
+const AccessTokenTTL = 24 * time.Hour
+
+// VerifyJWT memverifikasi token JWT yang diterima di header Authorization.
+// Token yang diterbitkan sebelum users.tokens_valid_after (misalnya sebelum reset password) ditolak.
+// Secara default hanya TokenAccess yang diterima; jenis lain harus disebutkan di types.
+func VerifyJWT(keys *helper.KeySet, users *mongo.Collection, types ...string) fiber.Handler {
+	if len(types) == 0 {
+		types = []string{TokenAccess}
 	}
+	return func(c *fiber.Ctx) error {
+		// Ambil token dari header Authorization
+		tokenString := c.Get("Authorization")
+
+		// Token harus diawali dengan "Bearer "
+		if len(tokenString) < 7 || tokenString[:7] != "Bearer " {
+			slog.WarnContext(c.UserContext(), "Invalid token format")
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token format",
+			})
+		}
 
-	// Ambil token tanpa kata "Bearer "
-	tokenString = tokenString[7:]
+		// Ambil token tanpa kata "Bearer "
+		tokenString = tokenString[7:]
 
-	// Verifikasi token
-	_, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Pastikan metode signing adalah HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			log.Println("Unexpected signing method")
-			return nil, fiber.ErrUnauthorized
+		// Verifikasi tanda tangan (berdasarkan kid), iss, aud, exp dan nbf
+		claims, err := keys.Parse(tokenString)
+		if err != nil {
+			slog.WarnContext(c.UserContext(), "Error verifying token", "error", err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token",
+			})
 		}
 
-		// Kembalikan secret key untuk validasi
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+		if !tokenTypeAllowed(claims, types) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token",
+			})
+		}
+		userID, err := primitive.ObjectIDFromHex(fmt.Sprint(claims[LocalUserID]))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token",
+			})
+		}
 
-	if err != nil {
-		log.Println("Error verifying token:", err)
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token",
-		})
+		validAfter, err := models.TokensRevokedBefore(c.UserContext(), users, userID)
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token",
+			})
+		}
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Error checking token revocation", "error", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to verify token",
+			})
+		}
+		issuedAt, _ := claims["iat"].(float64)
+		if !validAfter.IsZero() && int64(issuedAt) < validAfter.Unix() {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token has been revoked",
+			})
+		}
+
+		// Simpan identitas pengguna untuk handler berikutnya
+		for _, key := range []string{LocalUserID, LocalEmail, LocalRole} {
+			if value, ok := claims[key].(string); ok {
+				c.Locals(key, value)
+			}
+		}
+		c.Locals(LocalTokenType, TokenType(claims))
+		email, _ := claims[LocalEmail].(string)
+		role, _ := claims[LocalRole].(string)
+		c.Locals(LocalPrincipal, &Principal{UserID: userID, Email: email, Role: role})
+
+		// Lanjutkan ke handler berikutnya
+		return c.Next()
+	}
+}
+
+// OptionalAuth menjalankan verifyJWT hanya jika header Authorization ada,
+// sehingga pengunjung tanpa login (misalnya keranjang tamu) tetap diteruskan
+func OptionalAuth(verifyJWT fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Get(fiber.HeaderAuthorization) == "" {
+			return c.Next()
+		}
+		return verifyJWT(c)
+	}
+}
+
+// TokenType mengembalikan jenis token dari claims
+func TokenType(claims jwt.MapClaims) string {
+	if typ, ok := claims["typ"].(string); ok && typ != "" {
+		return typ
 	}
+	return TokenAccess
+}
+
+func tokenTypeAllowed(claims jwt.MapClaims, types []string) bool {
+	typ := TokenType(claims)
+	for _, t := range types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
 
-	// Lanjutkan ke handler berikutnya
-	return c.Next()
+// RequireRole hanya meneruskan request dari pengguna dengan salah satu role yang diberikan.
+// Harus dipasang setelah VerifyJWT.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals(LocalRole).(string)
+		for _, r := range roles {
+			if role == r {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Forbidden",
+		})
+	}
 }
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/helper"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyJWTRejectsOtherTokenTypes(t *testing.T) {
+	keys := helper.NewKeySet("gobiz", "gobiz-api")
+	keys.SetLegacySecret("test-secret")
+	app := fiber.New()
+	// Jenis token diperiksa sebelum database, jadi koleksi users tidak dibutuhkan
+	app.Get("/", VerifyJWT(keys, nil), func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	for _, typ := range []string{TokenMFAPending, TokenMFAEnroll} {
+		signed, err := keys.Sign(jwt.MapClaims{
+			"user_id": "64b7f0c2e1a2b3c4d5e6f708",
+			"typ":     typ,
+		}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+signed)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("%s token: status = %d, want 401", typ, resp.StatusCode)
+		}
+	}
+}
@@ -1,15 +1,64 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // Product represents a product in the database
 type Product struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
-	Name          string             `bson:"name" json:"name"`
-	Description   string             `bson:"description" json:"description"`
-	DiscountPrice int64              `bson:"discount_price" json:"discount_price"`
-	OriginalPrice int64              `bson:"original_price" json:"original_price"`
-	Image         string             `bson:"image" json:"image"`
-	CreatedAt     primitive.DateTime `json:"created_at" bson:"created_at"`
-	UpdatedAt     primitive.DateTime `json:"updated_at" bson:"updated_at"`
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	// SKU adalah kode unik produk, dipakai sebagai kunci impor katalog
+	SKU           string `bson:"sku,omitempty" json:"sku,omitempty"`
+	Name          string `bson:"name" json:"name"`
+	Description   string `bson:"description" json:"description"`
+	DiscountPrice int64  `bson:"discount_price" json:"discount_price"`
+	OriginalPrice int64  `bson:"original_price" json:"original_price"`
+	// Image adalah gambar utama; untuk produk dengan galeri selalu sama dengan gambar pertama Images
+	Image  string         `bson:"image" json:"image"`
+	Images []ProductImage `bson:"images,omitempty" json:"images,omitempty"`
+	// Stock nil berarti stok tidak dilacak (tidak dibatasi)
+	Stock *int `bson:"stock,omitempty" json:"stock,omitempty"`
+	// Options dan Variants diisi untuk produk yang dijual per varian (ukuran, rasa, kemasan).
+	// Harga dan stok di tingkat produk lalu diturunkan dari varian (lihat PrepareVariants).
+	Options    []ProductOption     `bson:"options,omitempty" json:"options,omitempty"`
+	Variants   []ProductVariant    `bson:"variants,omitempty" json:"variants,omitempty"`
+	CategoryID *primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	// Sales adalah diskon terjadwal; diterapkan dan dikembalikan oleh penjadwal harga
+	Sales []Sale `bson:"sales,omitempty" json:"sales,omitempty"`
+	// Rating adalah ringkasan ulasan yang tampil, diperbarui setiap ulasan berubah
+	Rating *RatingSummary `bson:"rating,omitempty" json:"rating,omitempty"`
+	// Tags bebas, disimpan dalam huruf kecil (lihat NormalizeTags)
+	Tags      []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	CreatedAt primitive.DateTime `json:"created_at" bson:"created_at"`
+	UpdatedAt primitive.DateTime `json:"updated_at" bson:"updated_at"`
+}
+
+// MaxProductTags membatasi jumlah tag per produk
+const MaxProductTags = 20
+
+// EffectivePrice mengembalikan harga yang dibayar pembeli saat ini (lihat EffectivePriceAt)
+func (p *Product) EffectivePrice() int64 {
+	return p.EffectivePriceAt(time.Now())
+}
+
+// EffectivePriceAt mengembalikan harga yang dibayar pada now: harga diskon (termasuk diskon terjadwal
+// yang sedang berjalan) jika ada, selain itu OriginalPrice. Untuk produk bervarian, harga varian termurah.
+func (p *Product) EffectivePriceAt(now time.Time) int64 {
+	if !p.HasVariants() {
+		return payable(p.discountAt(primitive.NilObjectID, p.DiscountPrice, now), p.OriginalPrice)
+	}
+	cheapest := p.variantPriceAt(&p.Variants[0], now)
+	for i := range p.Variants[1:] {
+		if price := p.variantPriceAt(&p.Variants[i+1], now); price < cheapest {
+			cheapest = price
+		}
+	}
+	return cheapest
+}
+
+// Available melaporkan apakah produk masih bisa dibeli (stok tidak dilacak atau masih ada)
+func (p *Product) Available() bool {
+	return p.Stock == nil || *p.Stock > 0
 }
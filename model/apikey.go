@@ -0,0 +1,141 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Scope yang bisa diberikan ke API key
+const (
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	ScopeOrdersRead    = "orders:read"
+)
+
+// APIKeyScopes adalah semua scope yang dikenal
+var APIKeyScopes = []string{ScopeProductsRead, ScopeProductsWrite, ScopeOrdersRead}
+
+// apiKeyPrefix memudahkan secret scanner mengenali API key yang bocor
+const apiKeyPrefix = "gbz_"
+
+// APIKey adalah kunci akses server-to-server milik admin atau merchant.
+// Hanya hash SHA-256 yang disimpan; kunci aslinya hanya ditampilkan sekali saat dibuat.
+type APIKey struct {
+	ID      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	OwnerID primitive.ObjectID `json:"owner_id" bson:"owner_id"`
+	Name    string             `json:"name" bson:"name"`
+	// Prefix adalah beberapa karakter awal kunci untuk ditampilkan di daftar
+	Prefix     string     `json:"prefix" bson:"prefix"`
+	KeyHash    string     `json:"-" bson:"key_hash"`
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at" bson:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+}
+
+// HasScope melaporkan apakah kunci punya scope tertentu
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidAPIKeyScope melaporkan apakah scope dikenal
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// EnsureAPIKeyIndexes membuat index lookup hash dan daftar per pemilik
+func EnsureAPIKeyIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "owner_id", Value: 1}}},
+	})
+	return err
+}
+
+// CreateAPIKey membuat kunci acak, menyimpan hash-nya ke key, lalu mengembalikan kunci asli
+func CreateAPIKey(ctx context.Context, collection *mongo.Collection, key *APIKey) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	key.ID = primitive.NewObjectID()
+	key.Prefix = raw[:len(apiKeyPrefix)+6]
+	key.KeyHash = HashToken(raw)
+	key.CreatedAt = time.Now()
+	if _, err := collection.InsertOne(ctx, key); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// FindActiveAPIKey mencari kunci yang belum dicabut dan belum kedaluwarsa.
+// Mengembalikan mongo.ErrNoDocuments jika kunci tidak valid.
+func FindActiveAPIKey(ctx context.Context, collection *mongo.Collection, raw string, now time.Time) (*APIKey, error) {
+	var key APIKey
+	err := collection.FindOne(ctx, bson.M{
+		"key_hash":   HashToken(raw),
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// TouchAPIKey mencatat waktu terakhir kunci dipakai. Paling sering sekali per menit
+// agar request beruntun tidak menulis ke database setiap kali.
+func TouchAPIKey(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, now time.Time) error {
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-time.Minute)}},
+		}},
+		bson.M{"$set": bson.M{"last_used_at": now}},
+	)
+	return err
+}
+
+// ListAPIKeys mengembalikan semua kunci milik pengguna, terbaru lebih dulu
+func ListAPIKeys(ctx context.Context, collection *mongo.Collection, ownerID primitive.ObjectID) ([]APIKey, error) {
+	cursor, err := collection.Find(ctx, bson.M{"owner_id": ownerID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	keys := []APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey mencabut kunci. filter menentukan siapa yang boleh mencabut (misalnya owner_id).
+// Mengembalikan false jika kunci tidak ditemukan atau sudah dicabut.
+func RevokeAPIKey(ctx context.Context, collection *mongo.Collection, filter bson.M, now time.Time) (bool, error) {
+	filter["revoked_at"] = bson.M{"$exists": false}
+	res, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": now}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
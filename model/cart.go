@@ -2,33 +2,102 @@ package models
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CartItem represents an item in the cart
 type CartItem struct {
 	ProductID primitive.ObjectID `json:"product_id,omitempty" bson:"product_id,omitempty"`
+	// VariantID kosong untuk produk tanpa varian
+	VariantID primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
 	Quantity  int                `json:"quantity,omitempty" bson:"quantity,omitempty"`
 }
 
+// CartLineKey mengidentifikasi satu baris keranjang: produk dan variannya
+type CartLineKey struct {
+	ProductID primitive.ObjectID
+	VariantID primitive.ObjectID
+}
+
+// Key mengembalikan identitas baris item
+func (i CartItem) Key() CartLineKey {
+	return CartLineKey{ProductID: i.ProductID, VariantID: i.VariantID}
+}
+
+// match mengembalikan kondisi MongoDB untuk elemen items dengan key ini.
+// variant_id null juga cocok dengan baris lama yang tidak punya field variant_id.
+func (k CartLineKey) match() bson.M {
+	m := bson.M{"product_id": k.ProductID, "variant_id": nil}
+	if !k.VariantID.IsZero() {
+		m["variant_id"] = k.VariantID
+	}
+	return m
+}
+
 // Cart represents a shopping cart
 type Cart struct {
-	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	UserID    primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	ID     primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	// GuestID diisi untuk keranjang pengunjung yang belum login (dari cart token)
+	GuestID   primitive.ObjectID `json:"guest_id,omitempty" bson:"guest_id,omitempty"`
 	Items     []CartItem         `json:"items,omitempty" bson:"items,omitempty"`
 	CreatedAt time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
 	UpdatedAt time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	// ExpiresAt hanya diisi untuk keranjang tamu; dihapus otomatis oleh TTL index
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	// Version naik setiap kali keranjang diubah, dipakai untuk ETag/If-Match
+	Version int64 `json:"version" bson:"version"`
+}
+
+// Error operasi keranjang
+var (
+	ErrCartNotFound        = errors.New("cart not found")
+	ErrCartItemNotFound    = errors.New("item not found in cart")
+	ErrCartVersionConflict = errors.New("cart has been modified")
+	ErrCartQuantityLimit   = errors.New("quantity exceeds the allowed maximum")
+)
+
+// Aturan penggabungan keranjang tamu ke keranjang pengguna untuk produk yang ada di keduanya
+const (
+	CartMergeSum         = "sum"
+	CartMergeMax         = "max"
+	CartMergePreferGuest = "prefer_guest"
+)
+
+// EnsureCartIndexes membuat index unik per pemilik (pengguna atau tamu) dan TTL untuk keranjang tamu
+func EnsureCartIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"user_id": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "guest_id", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"guest_id": bson.M{"$exists": true}}),
+		},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// ExpiredGuestCartsFilter mencocokkan keranjang tamu yang sudah kedaluwarsa tetapi belum dihapus TTL index
+func ExpiredGuestCartsFilter(now time.Time) bson.M {
+	return bson.M{"guest_id": bson.M{"$exists": true}, "expires_at": bson.M{"$lt": now}}
 }
 
-// AddItem adds an item to the cart
+// AddItem adds an item to the cart. Items are deduplicated on (product, variant).
 func (c *Cart) AddItem(item CartItem) {
 	// Check if item already exists, then update the quantity
 	for i, cartItem := range c.Items {
-		if cartItem.ProductID == item.ProductID {
+		if cartItem.Key() == item.Key() {
 			c.Items[i].Quantity += item.Quantity
 			return
 		}
@@ -37,10 +106,31 @@ func (c *Cart) AddItem(item CartItem) {
 	c.Items = append(c.Items, item)
 }
 
-// RemoveItem removes an item from the cart by its ProductID
-func (c *Cart) RemoveItem(productID primitive.ObjectID) {
+// Merge menggabungkan item keranjang tamu ke keranjang ini sesuai strategy
+func (c *Cart) Merge(guest []CartItem, strategy string) {
+	for _, item := range guest {
+		existing := -1
+		for i, cartItem := range c.Items {
+			if cartItem.Key() == item.Key() {
+				existing = i
+				break
+			}
+		}
+		switch {
+		case existing < 0 || strategy == CartMergeSum:
+			c.AddItem(item)
+		case strategy == CartMergePreferGuest:
+			c.Items[existing].Quantity = item.Quantity
+		case strategy == CartMergeMax && item.Quantity > c.Items[existing].Quantity:
+			c.Items[existing].Quantity = item.Quantity
+		}
+	}
+}
+
+// RemoveItem removes an item from the cart by its product and variant
+func (c *Cart) RemoveItem(key CartLineKey) {
 	for i, cartItem := range c.Items {
-		if cartItem.ProductID == productID {
+		if cartItem.Key() == key {
 			// Remove the item from the slice
 			c.Items = append(c.Items[:i], c.Items[i+1:]...)
 			return
@@ -49,24 +139,22 @@ func (c *Cart) RemoveItem(productID primitive.ObjectID) {
 }
 
 // TotalPrice calculates the total price of the cart by summing up prices of all items
-func (c *Cart) TotalPrice(productsCollection *mongo.Collection) (int64, error) {
+func (c *Cart) TotalPrice(ctx context.Context, productsCollection *mongo.Collection) (int64, error) {
 	var totalPrice int64
 
 	// Loop through each cart item and calculate the total price
 	for _, cartItem := range c.Items {
 		// Fetch the product from the products collection using ProductID
 		var product Product
-		err := productsCollection.FindOne(context.Background(), bson.M{"_id": cartItem.ProductID}).Decode(&product)
+		err := productsCollection.FindOne(ctx, bson.M{"_id": cartItem.ProductID}).Decode(&product)
 		if err != nil {
 			return 0, err // Return error if product is not found
 		}
 
-		// Determine which price to use (DiscountPrice if available, else OriginalPrice)
-		var price int64
-		if product.DiscountPrice > 0 {
-			price = product.DiscountPrice
-		} else {
-			price = product.OriginalPrice
+		// Harga varian jika produk punya varian, selain itu harga produk
+		price, _, err := product.Resolve(cartItem.VariantID)
+		if err != nil {
+			return 0, err
 		}
 
 		// Add the total price of the item (price * quantity)
@@ -76,3 +164,147 @@ func (c *Cart) TotalPrice(productsCollection *mongo.Collection) (int64, error) {
 	// Return the calculated total price
 	return totalPrice, nil
 }
+
+// versionFilter menyalin filter pemilik dan menambahkan syarat versi jika expectedVersion diisi.
+// Keranjang lama tanpa field version dianggap versi 0.
+func versionFilter(owner bson.M, expectedVersion *int64) bson.M {
+	filter := bson.M{}
+	for k, v := range owner {
+		filter[k] = v
+	}
+	if expectedVersion != nil {
+		if *expectedVersion == 0 {
+			filter["version"] = bson.M{"$in": bson.A{0, nil}}
+		} else {
+			filter["version"] = *expectedVersion
+		}
+	}
+	return filter
+}
+
+// AddCartItem menambah jumlah produk di keranjang secara atomik: $inc jika baris sudah ada,
+// atau $push bersyarat jika belum (keranjang dibuat jika belum ada dan expectedVersion nil).
+// set berisi field tambahan untuk $set, misalnya updated_at. Jumlah akhir tidak boleh melebihi maxQuantity.
+func AddCartItem(ctx context.Context, collection *mongo.Collection, owner bson.M, item CartItem, maxQuantity int, set bson.M, expectedVersion *int64) (*Cart, error) {
+	if item.Quantity < 1 || item.Quantity > maxQuantity {
+		return nil, ErrCartQuantityLimit
+	}
+	after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	// Dua percobaan: jika $push kalah balapan dengan request lain yang menambah baris yang sama,
+	// percobaan kedua akan memakai $inc
+	for attempt := 0; attempt < 2; attempt++ {
+		// 1. Baris sudah ada dan jumlah barunya masih dalam batas
+		filter := versionFilter(owner, expectedVersion)
+		match := item.Key().match()
+		match["quantity"] = bson.M{"$lte": maxQuantity - item.Quantity}
+		filter["items"] = bson.M{"$elemMatch": match}
+		var cart Cart
+		err := collection.FindOneAndUpdate(ctx, filter, withSet(bson.M{
+			"$inc": bson.M{"items.$.quantity": item.Quantity, "version": 1},
+		}, set), after).Decode(&cart)
+		if err == nil {
+			return &cart, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+
+		// 2. Baris belum ada: $push hanya jika produk dan varian ini belum ada di keranjang
+		filter = versionFilter(owner, expectedVersion)
+		filter["items"] = bson.M{"$not": bson.M{"$elemMatch": item.Key().match()}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetUpsert(expectedVersion == nil)
+		err = collection.FindOneAndUpdate(ctx, filter, withSet(bson.M{
+			"$push":        bson.M{"items": item},
+			"$inc":         bson.M{"version": 1},
+			"$setOnInsert": bson.M{"created_at": time.Now()},
+		}, set), opts).Decode(&cart)
+		if err == nil {
+			return &cart, nil
+		}
+		// Upsert bentrok dengan index unik pemilik: keranjang sudah ada dan baris sudah ada
+		if !mongo.IsDuplicateKeyError(err) && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+	return nil, explainCartMiss(ctx, collection, owner, item.Key(), expectedVersion, true)
+}
+
+// SetCartItemQuantity mengubah jumlah baris keranjang secara atomik. Jumlah 0 menghapus baris.
+func SetCartItemQuantity(ctx context.Context, collection *mongo.Collection, owner bson.M, key CartLineKey, quantity, maxQuantity int, set bson.M, expectedVersion *int64) (*Cart, error) {
+	if quantity < 0 || quantity > maxQuantity {
+		return nil, ErrCartQuantityLimit
+	}
+
+	filter := versionFilter(owner, expectedVersion)
+	filter["items"] = bson.M{"$elemMatch": key.match()}
+	update := bson.M{"$inc": bson.M{"version": 1}}
+	if quantity == 0 {
+		update["$pull"] = bson.M{"items": key.match()}
+	} else {
+		set = withField(set, "items.$.quantity", quantity)
+	}
+	update = withSet(update, set)
+
+	var cart Cart
+	err := collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&cart)
+	if err == mongo.ErrNoDocuments {
+		return nil, explainCartMiss(ctx, collection, owner, key, expectedVersion, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// RemoveCartItem menghapus baris produk (dan varian) dari keranjang
+func RemoveCartItem(ctx context.Context, collection *mongo.Collection, owner bson.M, key CartLineKey, set bson.M, expectedVersion *int64) (*Cart, error) {
+	return SetCartItemQuantity(ctx, collection, owner, key, 0, 0, set, expectedVersion)
+}
+
+// withSet menambahkan $set ke update jika set tidak kosong (MongoDB menolak $set kosong)
+func withSet(update, set bson.M) bson.M {
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	return update
+}
+
+// withField menyalin set lalu menambahkan satu field, tanpa mengubah map milik pemanggil
+func withField(set bson.M, key string, value interface{}) bson.M {
+	out := bson.M{key: value}
+	for k, v := range set {
+		out[k] = v
+	}
+	return out
+}
+
+// explainCartMiss mencari tahu mengapa update bersyarat tidak menemukan dokumen
+func explainCartMiss(ctx context.Context, collection *mongo.Collection, owner bson.M, key CartLineKey, expectedVersion *int64, adding bool) error {
+	var cart Cart
+	err := collection.FindOne(ctx, owner).Decode(&cart)
+	if err == mongo.ErrNoDocuments {
+		return ErrCartNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if expectedVersion != nil && cart.Version != *expectedVersion {
+		return ErrCartVersionConflict
+	}
+	for _, item := range cart.Items {
+		if item.Key() == key {
+			if adding {
+				return ErrCartQuantityLimit
+			}
+			// Baris ada tetapi update tetap gagal: keranjang berubah di tengah jalan
+			return ErrCartVersionConflict
+		}
+	}
+	if adding {
+		return ErrCartVersionConflict
+	}
+	return ErrCartItemNotFound
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Jenis operasi pada PATCH /api/cart
+const (
+	CartOpAdd    = "add"
+	CartOpSet    = "set"
+	CartOpRemove = "remove"
+	CartOpClear  = "clear"
+)
+
+// CartOperation adalah satu operasi dalam batch perubahan keranjang
+type CartOperation struct {
+	Op        string             `json:"op"`
+	ProductID primitive.ObjectID `json:"product_id,omitempty"`
+	VariantID primitive.ObjectID `json:"variant_id,omitempty"`
+	Quantity  int                `json:"quantity,omitempty"`
+}
+
+func (op CartOperation) key() CartLineKey {
+	return CartLineKey{ProductID: op.ProductID, VariantID: op.VariantID}
+}
+
+// CartOperationError menjelaskan operasi yang gagal divalidasi
+type CartOperationError struct {
+	Index     int                `json:"index"`
+	Op        string             `json:"op"`
+	ProductID primitive.ObjectID `json:"product_id,omitempty"`
+	VariantID primitive.ObjectID `json:"variant_id,omitempty"`
+	Error     string             `json:"error"`
+}
+
+// ApplyCartOperations menerapkan operasi berurutan ke salinan items. Mengembalikan items hasil,
+// error per operasi, dan index operasi add/set terakhir untuk setiap baris (untuk melaporkan
+// error produk, varian dan stok ke operasi yang tepat).
+func ApplyCartOperations(items []CartItem, ops []CartOperation, maxQuantity int) ([]CartItem, []CartOperationError, map[CartLineKey]int) {
+	cart := Cart{Items: append([]CartItem(nil), items...)}
+	var errs []CartOperationError
+	lastOp := map[CartLineKey]int{}
+	fail := func(i int, op CartOperation, msg string) {
+		errs = append(errs, CartOperationError{Index: i, Op: op.Op, ProductID: op.ProductID, VariantID: op.VariantID, Error: msg})
+	}
+
+	for i, op := range ops {
+		if op.Op != CartOpClear && op.ProductID.IsZero() {
+			fail(i, op, "product_id is required")
+			continue
+		}
+		switch op.Op {
+		case CartOpAdd:
+			current := cart.quantityOf(op.key())
+			if op.Quantity < 1 || current+op.Quantity > maxQuantity {
+				fail(i, op, fmt.Sprintf("quantity must be between 1 and %d per item", maxQuantity))
+				continue
+			}
+			cart.AddItem(CartItem{ProductID: op.ProductID, VariantID: op.VariantID, Quantity: op.Quantity})
+			lastOp[op.key()] = i
+		case CartOpSet:
+			if op.Quantity < 0 || op.Quantity > maxQuantity {
+				fail(i, op, fmt.Sprintf("quantity must be between 0 and %d", maxQuantity))
+				continue
+			}
+			cart.RemoveItem(op.key())
+			if op.Quantity > 0 {
+				cart.AddItem(CartItem{ProductID: op.ProductID, VariantID: op.VariantID, Quantity: op.Quantity})
+				lastOp[op.key()] = i
+			}
+		case CartOpRemove:
+			cart.RemoveItem(op.key())
+		case CartOpClear:
+			cart.Items = nil
+		default:
+			fail(i, op, "unknown op, expected add, set, remove or clear")
+		}
+	}
+	if cart.Items == nil {
+		cart.Items = []CartItem{}
+	}
+	return cart.Items, errs, lastOp
+}
+
+// ValidateCartProducts memeriksa bahwa setiap produk dan varian di items ada dan stoknya cukup.
+// products berisi produk yang ditemukan di database, lastOp dari ApplyCartOperations.
+func ValidateCartProducts(items []CartItem, products map[primitive.ObjectID]Product, ops []CartOperation, lastOp map[CartLineKey]int) []CartOperationError {
+	var errs []CartOperationError
+	for _, item := range items {
+		i, changed := lastOp[item.Key()]
+		if !changed {
+			// Baris lama yang tidak disentuh batch tidak divalidasi ulang
+			continue
+		}
+		fail := func(msg string) {
+			errs = append(errs, CartOperationError{Index: i, Op: ops[i].Op, ProductID: item.ProductID, VariantID: item.VariantID, Error: msg})
+		}
+		product, ok := products[item.ProductID]
+		if !ok {
+			fail("product not found")
+			continue
+		}
+		_, stock, err := product.Resolve(item.VariantID)
+		switch {
+		case err != nil:
+			fail(err.Error())
+		case stock != nil && item.Quantity > *stock:
+			fail(fmt.Sprintf("only %d left in stock", *stock))
+		}
+	}
+	return errs
+}
+
+// ReplaceCartItems mengganti seluruh items jika versi keranjang masih sama dengan version
+// (compare-and-swap). Keranjang dibuat jika belum ada. Mengembalikan ErrCartVersionConflict
+// jika keranjang diubah request lain sejak dibaca.
+func ReplaceCartItems(ctx context.Context, collection *mongo.Collection, owner bson.M, items []CartItem, version int64, set bson.M) (*Cart, error) {
+	filter := versionFilter(owner, &version)
+	var cart Cart
+	err := collection.FindOneAndUpdate(ctx, filter,
+		bson.M{
+			"$set":         withField(set, "items", items),
+			"$inc":         bson.M{"version": 1},
+			"$setOnInsert": bson.M{"created_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetUpsert(true),
+	).Decode(&cart)
+	// Upsert bentrok dengan index unik pemilik berarti keranjang ada dengan versi lain
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrCartVersionConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// CartWriteAttempts adalah jumlah percobaan read-modify-write keranjang sebelum menyerah
+// karena request lain terus mengubah keranjang yang sama
+const CartWriteAttempts = 3
+
+// MergeCartItems menggabungkan items tamu ke keranjang milik owner dengan strategy, membatasi
+// jumlah per baris ke maxQuantity, lalu menyimpannya dengan ReplaceCartItems. Jika keranjang
+// diubah request lain di antara baca dan tulis, penggabungan diulang dari data terbaru.
+func MergeCartItems(ctx context.Context, collection *mongo.Collection, owner bson.M, guest []CartItem, strategy string, maxQuantity int, set bson.M) (*Cart, error) {
+	for attempt := 0; attempt < CartWriteAttempts; attempt++ {
+		var current Cart
+		err := collection.FindOne(ctx, owner).Decode(&current)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		current.Merge(guest, strategy)
+		for i := range current.Items {
+			current.Items[i].Quantity = min(current.Items[i].Quantity, maxQuantity)
+		}
+
+		cart, err := ReplaceCartItems(ctx, collection, owner, current.Items, current.Version, set)
+		if err == ErrCartVersionConflict {
+			continue
+		}
+		return cart, err
+	}
+	return nil, ErrCartVersionConflict
+}
+
+func (c *Cart) quantityOf(key CartLineKey) int {
+	for _, item := range c.Items {
+		if item.Key() == key {
+			return item.Quantity
+		}
+	}
+	return 0
+}
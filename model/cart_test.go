@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCartMerge(t *testing.T) {
+	shared, userOnly, guestOnly := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	want := map[string]int{
+		CartMergeSum:         5,
+		CartMergeMax:         3,
+		CartMergePreferGuest: 2,
+	}
+	for strategy, sharedQty := range want {
+		cart := Cart{Items: []CartItem{{ProductID: shared, Quantity: 3}, {ProductID: userOnly, Quantity: 1}}}
+		cart.Merge([]CartItem{{ProductID: shared, Quantity: 2}, {ProductID: guestOnly, Quantity: 4}}, strategy)
+
+		got := map[primitive.ObjectID]int{}
+		for _, item := range cart.Items {
+			got[item.ProductID] = item.Quantity
+		}
+		if len(got) != 3 || got[shared] != sharedQty || got[userOnly] != 1 || got[guestOnly] != 4 {
+			t.Errorf("%s: got %v", strategy, got)
+		}
+	}
+}
+
+func TestVersionFilter(t *testing.T) {
+	owner := bson.M{"user_id": primitive.NewObjectID()}
+	if f := versionFilter(owner, nil); len(f) != 1 {
+		t.Errorf("no If-Match should not add a version condition: %v", f)
+	}
+	v := int64(3)
+	if f := versionFilter(owner, &v); f["version"] != int64(3) {
+		t.Errorf("version = %v", f["version"])
+	}
+	zero := int64(0)
+	// Keranjang lama tanpa field version dianggap versi 0
+	if f := versionFilter(owner, &zero); f["version"] == nil {
+		t.Error("version 0 should match missing field")
+	}
+	if len(owner) != 1 {
+		t.Error("owner filter must not be modified")
+	}
+}
+
+func TestApplyCartOperations(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	items := []CartItem{{ProductID: a, Quantity: 2}}
+	ops := []CartOperation{
+		{Op: CartOpAdd, ProductID: a, Quantity: 1},
+		{Op: CartOpSet, ProductID: b, Quantity: 5},
+		{Op: CartOpRemove, ProductID: primitive.NewObjectID()},
+	}
+	got, errs, lastOp := ApplyCartOperations(items, ops, 10)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+	if len(got) != 2 || got[0].Quantity != 3 || got[1].Quantity != 5 || lastOp[CartLineKey{ProductID: b}] != 1 {
+		t.Errorf("items = %+v, lastOp = %v", got, lastOp)
+	}
+	if items[0].Quantity != 2 {
+		t.Error("input items must not be modified")
+	}
+
+	stock := 4
+	products := map[primitive.ObjectID]Product{a: {ID: a}, b: {ID: b, Stock: &stock}}
+	errs = ValidateCartProducts(got, products, ops, lastOp)
+	if len(errs) != 1 || errs[0].Index != 1 {
+		t.Errorf("expected stock error on op 1, got %+v", errs)
+	}
+
+	got, errs, _ = ApplyCartOperations(items, []CartOperation{
+		{Op: CartOpClear},
+		{Op: CartOpAdd, ProductID: a, Quantity: 11},
+		{Op: "explode", ProductID: a},
+	}, 10)
+	if len(errs) != 2 || errs[0].Index != 1 || errs[1].Index != 2 || len(got) != 0 {
+		t.Errorf("items = %+v, errs = %+v", got, errs)
+	}
+}
+
+func TestMergeCartItemsRetriesOnConflict(t *testing.T) {
+	shared, userOnly := primitive.NewObjectID(), primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	cartDoc := func(version int64, items ...CartItem) bson.D {
+		return bson.D{{Key: "user_id", Value: userID}, {Key: "items", Value: items}, {Key: "version", Value: version}}
+	}
+	conflict := mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Name: "DuplicateKey", Message: "E11000 duplicate key error"})
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("retry", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			// Baca pertama: versi 1, lalu request lain menaikkan versi sebelum ditulis
+			mtest.CreateCursorResponse(0, "test.carts", mtest.FirstBatch, cartDoc(1, CartItem{ProductID: shared, Quantity: 1})),
+			conflict,
+			// Baca ulang melihat baris yang ditambahkan request lain
+			mtest.CreateCursorResponse(0, "test.carts", mtest.FirstBatch, cartDoc(2,
+				CartItem{ProductID: shared, Quantity: 1}, CartItem{ProductID: userOnly, Quantity: 2})),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: cartDoc(3)}),
+		)
+
+		_, err := MergeCartItems(context.Background(), mt.Coll, bson.M{"user_id": userID},
+			[]CartItem{{ProductID: shared, Quantity: 9}}, CartMergeSum, 5, bson.M{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Tulisan terakhir memakai versi hasil baca ulang dan tetap memuat baris dari request lain
+		started := mt.GetAllStartedEvents()
+		if len(started) != 4 {
+			t.Fatalf("commands = %d, want 4", len(started))
+		}
+		cmd := started[3].Command
+		if v := cmd.Lookup("query", "version").AsInt64(); v != 2 {
+			t.Errorf("write version = %d, want 2", v)
+		}
+		var items []CartItem
+		if err := cmd.Lookup("update", "$set", "items").Unmarshal(&items); err != nil {
+			t.Fatal(err)
+		}
+		got := map[primitive.ObjectID]int{}
+		for _, item := range items {
+			got[item.ProductID] = item.Quantity
+		}
+		if len(got) != 2 || got[shared] != 5 || got[userOnly] != 2 {
+			t.Errorf("items = %v, want shared capped at 5 and userOnly kept", got)
+		}
+	})
+
+	mt.Run("gives up", func(mt *mtest.T) {
+		for i := 0; i < CartWriteAttempts; i++ {
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.carts", mtest.FirstBatch, cartDoc(int64(i))), conflict)
+		}
+		_, err := MergeCartItems(context.Background(), mt.Coll, bson.M{"user_id": userID},
+			[]CartItem{{ProductID: shared, Quantity: 1}}, CartMergeSum, 5, bson.M{})
+		if err != ErrCartVersionConflict {
+			t.Fatalf("err = %v, want ErrCartVersionConflict", err)
+		}
+	})
+}
@@ -0,0 +1,630 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Format file katalog
+const (
+	CatalogCSV   = "csv"
+	CatalogJSONL = "jsonl"
+)
+
+// Hasil impor satu baris
+const (
+	importCreated   = "created"
+	importUpdated   = "updated"
+	importUnchanged = "unchanged"
+)
+
+// MaxSKULength adalah panjang maksimal SKU produk
+const MaxSKULength = 64
+
+// MaxImportErrors membatasi jumlah error baris yang disimpan; baris gagal berikutnya tetap dihitung
+const MaxImportErrors = 1000
+
+// importProgressEvery adalah jumlah baris antar laporan progres
+const importProgressEvery = 200
+
+// ErrUnknownCatalogFormat dikembalikan untuk format selain csv dan jsonl
+var ErrUnknownCatalogFormat = errors.New("format must be csv or jsonl")
+
+// catalogColumns adalah kolom CSV katalog. Tag dipisah "|", options dan variants berisi JSON.
+var catalogColumns = []string{
+	"sku", "name", "description", "category", "tags", "discount_price", "original_price",
+	"stock", "image", "options", "variants",
+}
+
+// CatalogRow adalah satu produk dalam file impor atau ekspor. Category berisi slug kategori.
+// ID varian diabaikan saat impor; varian dicocokkan dengan SKU-nya.
+type CatalogRow struct {
+	SKU           string           `json:"sku"`
+	Name          string           `json:"name"`
+	Description   string           `json:"description,omitempty"`
+	Category      string           `json:"category,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	DiscountPrice int64            `json:"discount_price"`
+	OriginalPrice int64            `json:"original_price"`
+	Stock         *int             `json:"stock,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	Options       []ProductOption  `json:"options,omitempty"`
+	Variants      []ProductVariant `json:"variants,omitempty"`
+}
+
+// ImportRowError menjelaskan baris yang gagal diimpor. Row adalah nomor baris di file.
+type ImportRowError struct {
+	Row   int    `bson:"row" json:"row"`
+	SKU   string `bson:"sku,omitempty" json:"sku,omitempty"`
+	Error string `bson:"error" json:"error"`
+}
+
+// ImportStats adalah progres dan hasil impor katalog
+type ImportStats struct {
+	BytesRead int64            `bson:"bytes_read" json:"bytes_read"`
+	Rows      int              `bson:"rows" json:"rows"`
+	Created   int              `bson:"created" json:"created"`
+	Updated   int              `bson:"updated" json:"updated"`
+	Unchanged int              `bson:"unchanged" json:"unchanged"`
+	Failed    int              `bson:"failed" json:"failed"`
+	Errors    []ImportRowError `bson:"errors" json:"errors"`
+}
+
+// rowError adalah kesalahan data satu baris; error lain menghentikan impor
+type rowError string
+
+func (e rowError) Error() string { return string(e) }
+
+// CatalogImporter mengimpor produk dari CSV atau JSONL dengan upsert berdasarkan SKU
+type CatalogImporter struct {
+	Products   *mongo.Collection
+	Categories *mongo.Collection
+	History    *mongo.Collection
+	// DryRun memvalidasi semua baris dan melaporkan hasilnya tanpa menulis ke database
+	DryRun bool
+	// Actor dicatat di riwayat harga untuk perubahan harga produk yang sudah ada
+	Actor PriceActor
+	// Progress dipanggil berkala selama impor dan sekali di akhir
+	Progress func(ImportStats)
+
+	categories map[string]primitive.ObjectID
+	seen       map[string]bool
+	stats      ImportStats
+}
+
+// Import membaca r sampai habis dan mengimpor setiap baris. Kesalahan data per baris dicatat di
+// ImportStats.Errors; error yang dikembalikan berarti impor berhenti di tengah (misalnya header
+// tidak valid atau database tidak bisa diakses), dengan statistik sampai titik itu.
+func (im *CatalogImporter) Import(ctx context.Context, r io.Reader, format string) (*ImportStats, error) {
+	im.stats = ImportStats{Errors: []ImportRowError{}}
+	im.seen = map[string]bool{}
+	if err := im.loadCategories(ctx); err != nil {
+		return &im.stats, err
+	}
+	counter := &countingReader{r: r}
+	var err error
+	switch format {
+	case CatalogCSV:
+		err = im.importCSV(ctx, counter)
+	case CatalogJSONL:
+		err = im.importJSONL(ctx, counter)
+	default:
+		err = ErrUnknownCatalogFormat
+	}
+	im.stats.BytesRead = counter.n
+	if im.Progress != nil {
+		im.Progress(im.stats)
+	}
+	return &im.stats, err
+}
+
+func (im *CatalogImporter) loadCategories(ctx context.Context) error {
+	categories, err := ListCategories(ctx, im.Categories)
+	if err != nil {
+		return err
+	}
+	im.categories = make(map[string]primitive.ObjectID, len(categories))
+	for _, category := range categories {
+		im.categories[category.Slug] = category.ID
+	}
+	return nil
+}
+
+func (im *CatalogImporter) importCSV(ctx context.Context, r *countingReader) error {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("read csv header: %w", err)
+	}
+	columns := make([]string, len(header))
+	known := map[string]bool{}
+	for _, name := range catalogColumns {
+		known[name] = true
+	}
+	has := map[string]bool{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known[name] || has[name] {
+			return fmt.Errorf("csv header: unknown or duplicate column %q", name)
+		}
+		columns[i], has[name] = name, true
+	}
+	if !has["sku"] || !has["name"] {
+		return errors.New("csv header: columns sku and name are required")
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var line int
+		var rowErr error
+		var row CatalogRow
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			line, rowErr = parseErr.StartLine, rowError(parseErr.Err.Error())
+		case err != nil:
+			return err
+		default:
+			line, _ = reader.FieldPos(0)
+			if row, rowErr = csvRow(columns, record); rowErr == nil {
+				rowErr = im.importRow(ctx, row)
+			}
+		}
+		if err := im.record(ctx, line, row.SKU, rowErr); err != nil {
+			return err
+		}
+		r.flush(&im.stats)
+	}
+}
+
+// csvRow mengubah satu record CSV menjadi CatalogRow
+func csvRow(columns, record []string) (CatalogRow, error) {
+	var row CatalogRow
+	for i, value := range record {
+		value = strings.TrimSpace(value)
+		var err error
+		switch columns[i] {
+		case "sku":
+			row.SKU = value
+		case "name":
+			row.Name = value
+		case "description":
+			row.Description = value
+		case "category":
+			row.Category = value
+		case "tags":
+			if value != "" {
+				row.Tags = strings.Split(value, "|")
+			}
+		case "discount_price":
+			row.DiscountPrice, err = parseCSVInt(value)
+		case "original_price":
+			row.OriginalPrice, err = parseCSVInt(value)
+		case "stock":
+			if value != "" {
+				var stock int64
+				stock, err = parseCSVInt(value)
+				n := int(stock)
+				row.Stock = &n
+			}
+		case "image":
+			row.Image = value
+		case "options":
+			if value != "" {
+				err = json.Unmarshal([]byte(value), &row.Options)
+			}
+		case "variants":
+			if value != "" {
+				err = json.Unmarshal([]byte(value), &row.Variants)
+			}
+		}
+		if err != nil {
+			return row, rowError(fmt.Sprintf("column %s: %v", columns[i], err))
+		}
+	}
+	return row, nil
+}
+
+func parseCSVInt(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("must be an integer")
+	}
+	return n, nil
+}
+
+func (im *CatalogImporter) importJSONL(ctx context.Context, r *countingReader) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var row CatalogRow
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.DisallowUnknownFields()
+			rowErr := decoder.Decode(&row)
+			if rowErr != nil {
+				rowErr = rowError("invalid JSON: " + rowErr.Error())
+			} else {
+				rowErr = im.importRow(ctx, row)
+			}
+			if err := im.record(ctx, line, row.SKU, rowErr); err != nil {
+				return err
+			}
+			r.flush(&im.stats)
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// record mencatat hasil satu baris. Kesalahan baris dicatat dan nil dikembalikan; error lain
+// (termasuk pembatalan ctx) dikembalikan agar impor berhenti.
+func (im *CatalogImporter) record(ctx context.Context, line int, sku string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var re rowError
+	if !errors.As(err, &re) {
+		return err
+	}
+	im.stats.Rows++
+	im.stats.Failed++
+	if len(im.stats.Errors) < MaxImportErrors {
+		im.stats.Errors = append(im.stats.Errors, ImportRowError{Row: line, SKU: sku, Error: err.Error()})
+	}
+	im.report()
+	return ctx.Err()
+}
+
+func (im *CatalogImporter) report() {
+	if im.Progress != nil && im.stats.Rows%importProgressEvery == 0 {
+		im.Progress(im.stats)
+	}
+}
+
+// importRow memvalidasi dan menyimpan satu baris. Mengembalikan rowError untuk data yang salah.
+func (im *CatalogImporter) importRow(ctx context.Context, row CatalogRow) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	next, err := im.product(row)
+	if err != nil {
+		return err
+	}
+	if im.seen[next.SKU] {
+		return rowError("duplicate sku in file")
+	}
+	im.seen[next.SKU] = true
+
+	var existing Product
+	err = im.Products.FindOne(ctx, bson.M{"sku": next.SKU}).Decode(&existing)
+	var outcome string
+	switch {
+	case err == mongo.ErrNoDocuments:
+		outcome, err = importCreated, im.create(ctx, next)
+	case err == nil:
+		outcome, err = im.update(ctx, &existing, next)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return rowError("sku or a variant sku is already used by another product")
+	}
+	if err != nil {
+		return err
+	}
+
+	im.stats.Rows++
+	switch outcome {
+	case importCreated:
+		im.stats.Created++
+	case importUpdated:
+		im.stats.Updated++
+	default:
+		im.stats.Unchanged++
+	}
+	im.report()
+	return nil
+}
+
+// product memvalidasi baris dan mengubahnya menjadi produk baru (tanpa ID)
+func (im *CatalogImporter) product(row CatalogRow) (*Product, error) {
+	p := &Product{
+		SKU:         strings.TrimSpace(row.SKU),
+		Name:        strings.TrimSpace(row.Name),
+		Description: row.Description,
+		Tags:        NormalizeTags(row.Tags),
+		Image:       row.Image,
+		Options:     row.Options,
+		Variants:    row.Variants,
+	}
+	switch {
+	case p.SKU == "" || len(p.SKU) > MaxSKULength:
+		return nil, rowError(fmt.Sprintf("sku is required and must be at most %d characters", MaxSKULength))
+	case p.Name == "":
+		return nil, rowError("name is required")
+	case len(p.Tags) > MaxProductTags:
+		return nil, rowError(fmt.Sprintf("a product can have at most %d tags", MaxProductTags))
+	}
+	if len(p.Tags) == 0 {
+		p.Tags = nil
+	}
+	if row.Category != "" {
+		id, ok := im.categories[row.Category]
+		if !ok {
+			return nil, rowError(fmt.Sprintf("category %q not found", row.Category))
+		}
+		p.CategoryID = &id
+	}
+	if p.HasVariants() || len(p.Options) > 0 {
+		for i := range p.Variants {
+			p.Variants[i].ID = primitive.NilObjectID
+		}
+		if err := p.PrepareVariants(); err != nil {
+			return nil, rowError(err.Error())
+		}
+		return p, nil
+	}
+	if row.OriginalPrice <= 0 || row.DiscountPrice < 0 || row.DiscountPrice > row.OriginalPrice {
+		return nil, rowError(ErrInvalidPrice.Error())
+	}
+	if row.Stock != nil && *row.Stock < 0 {
+		return nil, rowError("stock must not be negative")
+	}
+	p.DiscountPrice, p.OriginalPrice, p.Stock = row.DiscountPrice, row.OriginalPrice, row.Stock
+	return p, nil
+}
+
+func (im *CatalogImporter) create(ctx context.Context, p *Product) error {
+	if im.DryRun {
+		return nil
+	}
+	now := primitive.NewDateTimeFromTime(time.Now())
+	p.ID = primitive.NewObjectID()
+	p.CreatedAt, p.UpdatedAt = now, now
+	_, err := im.Products.InsertOne(ctx, p)
+	return err
+}
+
+// catalogFields adalah field produk yang dikelola impor, untuk mendeteksi baris yang tidak berubah
+type catalogFields struct {
+	Name, Description, Image     string
+	CategoryID                   *primitive.ObjectID
+	Tags                         []string
+	DiscountPrice, OriginalPrice int64
+	Stock                        *int
+	Options                      []ProductOption
+	Variants                     []ProductVariant
+}
+
+func fieldsOf(p *Product) catalogFields {
+	f := catalogFields{
+		Name: p.Name, Description: p.Description, Image: p.Image, CategoryID: p.CategoryID,
+		Tags: p.Tags, DiscountPrice: p.DiscountPrice, OriginalPrice: p.OriginalPrice, Stock: p.Stock,
+		Options: p.Options, Variants: p.Variants,
+	}
+	if len(f.Tags) == 0 {
+		f.Tags = nil
+	}
+	if len(f.Options) == 0 {
+		f.Options = nil
+	}
+	if len(f.Variants) == 0 {
+		f.Variants = nil
+	}
+	return f
+}
+
+// update menerapkan baris ke produk yang sudah ada. ID varian dipertahankan untuk SKU yang sama
+// agar keranjang dan wishlist tetap valid; perubahan harga dicatat di riwayat harga. Gambar dari
+// file hanya dipakai untuk produk tanpa galeri, dan gambar kosong tidak menghapus gambar lama.
+func (im *CatalogImporter) update(ctx context.Context, existing, next *Product) (string, error) {
+	old := map[string]*ProductVariant{}
+	for i := range existing.Variants {
+		old[existing.Variants[i].SKU] = &existing.Variants[i]
+	}
+	for i := range next.Variants {
+		if v := old[next.Variants[i].SKU]; v != nil {
+			next.Variants[i].ID = v.ID
+		}
+	}
+	if len(existing.Images) > 0 || next.Image == "" {
+		next.Image = existing.Image
+	}
+	if reflect.DeepEqual(fieldsOf(existing), fieldsOf(next)) {
+		return importUnchanged, nil
+	}
+
+	now := time.Now()
+	var changes []PriceChange
+	addChange := func(variantID primitive.ObjectID, oldDiscount, oldOriginal, newDiscount, newOriginal int64) error {
+		if oldDiscount == newDiscount && oldOriginal == newOriginal {
+			return nil
+		}
+		for i := range existing.Sales {
+			if s := &existing.Sales[i]; s.VariantID == variantID && s.pending() && (s.Status == SaleActive || s.covers(now)) {
+				return rowError(ErrSaleActive.Error())
+			}
+		}
+		changes = append(changes, PriceChange{
+			ProductID: existing.ID, VariantID: variantID,
+			OldDiscountPrice: oldDiscount, OldOriginalPrice: oldOriginal,
+			NewDiscountPrice: newDiscount, NewOriginalPrice: newOriginal,
+			Source: PriceSourceImport, ChangedAt: now,
+		})
+		return nil
+	}
+	if !existing.HasVariants() && !next.HasVariants() {
+		if err := addChange(primitive.NilObjectID, existing.DiscountPrice, existing.OriginalPrice, next.DiscountPrice, next.OriginalPrice); err != nil {
+			return "", err
+		}
+	}
+	for i := range next.Variants {
+		v := &next.Variants[i]
+		if prev := old[v.SKU]; prev != nil {
+			if err := addChange(v.ID, prev.DiscountPrice, prev.OriginalPrice, v.DiscountPrice, v.OriginalPrice); err != nil {
+				return "", err
+			}
+		}
+	}
+	if im.DryRun {
+		return importUpdated, nil
+	}
+
+	set := bson.M{
+		"name":           next.Name,
+		"description":    next.Description,
+		"image":          next.Image,
+		"discount_price": next.DiscountPrice,
+		"original_price": next.OriginalPrice,
+		"updated_at":     primitive.NewDateTimeFromTime(now),
+	}
+	unset := bson.M{}
+	for field, value := range map[string]interface{}{
+		"category_id": next.CategoryID,
+		"tags":        next.Tags,
+		"stock":       next.Stock,
+		"options":     next.Options,
+		"variants":    next.Variants,
+	} {
+		if reflect.ValueOf(value).IsNil() {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	res, err := im.Products.UpdateOne(ctx, bson.M{"_id": existing.ID, "updated_at": existing.UpdatedAt}, update)
+	if err != nil {
+		return "", err
+	}
+	if res.MatchedCount == 0 {
+		return "", rowError("product was modified during the import, please retry")
+	}
+	return importUpdated, recordPriceChanges(ctx, im.History, changes, im.Actor)
+}
+
+// countingReader menghitung byte yang sudah dibaca untuk progres impor
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) flush(stats *ImportStats) {
+	stats.BytesRead = c.n
+}
+
+// ExportCatalog menulis semua produk ke w dalam format csv atau jsonl, diurutkan berdasarkan ID.
+// Mengembalikan jumlah produk yang ditulis.
+func ExportCatalog(ctx context.Context, products, categories *mongo.Collection, w io.Writer, format string) (int, error) {
+	if format != CatalogCSV && format != CatalogJSONL {
+		return 0, ErrUnknownCatalogFormat
+	}
+	list, err := ListCategories(ctx, categories)
+	if err != nil {
+		return 0, err
+	}
+	slugs := make(map[primitive.ObjectID]string, len(list))
+	for _, category := range list {
+		slugs[category.ID] = category.Slug
+	}
+
+	cursor, err := products.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var csvWriter *csv.Writer
+	encoder := json.NewEncoder(w)
+	if format == CatalogCSV {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(catalogColumns); err != nil {
+			return 0, err
+		}
+	}
+	count := 0
+	for cursor.Next(ctx) {
+		var p Product
+		if err := cursor.Decode(&p); err != nil {
+			return count, err
+		}
+		row := CatalogRow{
+			SKU: p.SKU, Name: p.Name, Description: p.Description, Tags: p.Tags,
+			DiscountPrice: p.DiscountPrice, OriginalPrice: p.OriginalPrice, Stock: p.Stock,
+			Image: p.Image, Options: p.Options, Variants: p.Variants,
+		}
+		if p.CategoryID != nil {
+			row.Category = slugs[*p.CategoryID]
+		}
+		if csvWriter != nil {
+			err = csvWriter.Write(row.csvRecord())
+		} else {
+			err = encoder.Encode(row)
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return count, err
+		}
+	}
+	return count, cursor.Err()
+}
+
+// csvRecord mengubah baris menjadi record CSV sesuai catalogColumns
+func (row CatalogRow) csvRecord() []string {
+	stock := ""
+	if row.Stock != nil {
+		stock = strconv.Itoa(*row.Stock)
+	}
+	jsonCell := func(v interface{}, empty bool) string {
+		if empty {
+			return ""
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return []string{
+		row.SKU, row.Name, row.Description, row.Category, strings.Join(row.Tags, "|"),
+		strconv.FormatInt(row.DiscountPrice, 10), strconv.FormatInt(row.OriginalPrice, 10),
+		stock, row.Image, jsonCell(row.Options, len(row.Options) == 0), jsonCell(row.Variants, len(row.Variants) == 0),
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCatalogCSVRoundTrip(t *testing.T) {
+	stock := 7
+	row := CatalogRow{
+		SKU: "KOPI-1", Name: "Kopi, susu", Category: "minuman", Tags: []string{"kopi", "manis"},
+		DiscountPrice: 18000, OriginalPrice: 20000, Stock: &stock,
+		Options:  []ProductOption{{Name: "size", Values: []string{"S", "L"}}},
+		Variants: []ProductVariant{{SKU: "KOPI-1-S", Options: map[string]string{"size": "S"}, OriginalPrice: 20000}},
+	}
+	got, err := csvRow(catalogColumns, row.csvRecord())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SKU != row.SKU || got.Name != row.Name || strings.Join(got.Tags, "|") != "kopi|manis" ||
+		got.Stock == nil || *got.Stock != 7 || len(got.Variants) != 1 || got.Variants[0].Options["size"] != "S" {
+		t.Errorf("round trip = %+v", got)
+	}
+
+	if _, err := csvRow([]string{"sku", "original_price"}, []string{"A", "mahal"}); err == nil {
+		t.Error("non-integer price should fail")
+	}
+}
+
+func TestCatalogImporterProduct(t *testing.T) {
+	category := primitive.NewObjectID()
+	im := CatalogImporter{categories: map[string]primitive.ObjectID{"minuman": category}}
+
+	p, err := im.product(CatalogRow{SKU: " A-1 ", Name: "Teh", Category: "minuman", Tags: []string{"Teh", "teh"}, OriginalPrice: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.SKU != "A-1" || *p.CategoryID != category || len(p.Tags) != 1 {
+		t.Errorf("product = %+v", p)
+	}
+
+	for _, row := range []CatalogRow{
+		{Name: "no sku", OriginalPrice: 1},
+		{SKU: "B", OriginalPrice: 1},
+		{SKU: "C", Name: "c", Category: "unknown", OriginalPrice: 1},
+		{SKU: "D", Name: "d", DiscountPrice: 9, OriginalPrice: 5},
+		{SKU: "E", Name: "e", Options: []ProductOption{{Name: "size", Values: []string{"S"}}}},
+	} {
+		if _, err := im.product(row); err == nil {
+			t.Errorf("%s: expected error", row.SKU)
+		} else if _, ok := err.(rowError); !ok {
+			t.Errorf("%s: %v is not a row error", row.SKU, err)
+		}
+	}
+}
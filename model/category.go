@@ -0,0 +1,279 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Error operasi kategori
+var (
+	ErrCategoryNotFound       = errors.New("category not found")
+	ErrCategoryParentNotFound = errors.New("parent category not found")
+	ErrCategoryCycle          = errors.New("category cannot be moved under itself or its descendants")
+	ErrCategoryInUse          = errors.New("category still has subcategories or products")
+)
+
+// Category adalah simpul pohon kategori produk.
+// Ancestors menyimpan jalur dari akar sampai parent, sehingga semua turunan sebuah kategori
+// bisa dicari dengan satu query {ancestors: id}.
+type Category struct {
+	ID        primitive.ObjectID   `json:"_id" bson:"_id,omitempty"`
+	Name      string               `json:"name" bson:"name"`
+	Slug      string               `json:"slug" bson:"slug"`
+	ParentID  *primitive.ObjectID  `json:"parent_id" bson:"parent_id"`
+	Ancestors []primitive.ObjectID `json:"ancestors" bson:"ancestors"`
+	SortOrder int                  `json:"sort_order" bson:"sort_order"`
+	CreatedAt time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+// CategoryNode adalah kategori beserta anak-anaknya untuk respons berbentuk pohon
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// IsValidSlug melaporkan apakah slug hanya berisi huruf kecil, angka dan tanda hubung
+func IsValidSlug(slug string) bool {
+	return len(slug) <= 100 && slugPattern.MatchString(slug)
+}
+
+// Slugify membuat slug dari nama, misalnya "Makanan & Minuman" menjadi "makanan-minuman"
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// EnsureCategoryIndexes membuat index unik pada slug dan index untuk pencarian turunan
+func EnsureCategoryIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+	})
+	return err
+}
+
+// FindCategory mencari kategori berdasarkan ID (hex) atau slug
+func FindCategory(ctx context.Context, collection *mongo.Collection, idOrSlug string) (*Category, error) {
+	filter := bson.M{"slug": idOrSlug}
+	if id, err := primitive.ObjectIDFromHex(idOrSlug); err == nil {
+		filter = bson.M{"_id": id}
+	}
+	var category Category
+	err := collection.FindOne(ctx, filter).Decode(&category)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// ListCategories mengembalikan semua kategori terurut berdasarkan sort_order lalu nama
+func ListCategories(ctx context.Context, collection *mongo.Collection) ([]Category, error) {
+	cursor, err := collection.Find(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "sort_order", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	categories := []Category{}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// CategorySubtreeIDs mengembalikan ID kategori beserta semua turunannya
+func CategorySubtreeIDs(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := collection.Find(ctx, bson.M{"ancestors": id}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var descendants []Category
+	if err := cursor.All(ctx, &descendants); err != nil {
+		return nil, err
+	}
+	ids := []primitive.ObjectID{id}
+	for _, d := range descendants {
+		ids = append(ids, d.ID)
+	}
+	return ids, nil
+}
+
+// categoryPath mengembalikan ancestors untuk anak dari parentID (kosong untuk kategori akar)
+func categoryPath(ctx context.Context, collection *mongo.Collection, parentID *primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if parentID == nil {
+		return []primitive.ObjectID{}, nil
+	}
+	var parent Category
+	err := collection.FindOne(ctx, bson.M{"_id": *parentID}).Decode(&parent)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrCategoryParentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]primitive.ObjectID{}, parent.Ancestors...), parent.ID), nil
+}
+
+// CreateCategory menyimpan kategori baru dan mengisi Ancestors dari parent
+func CreateCategory(ctx context.Context, collection *mongo.Collection, category *Category) error {
+	path, err := categoryPath(ctx, collection, category.ParentID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	category.ID = primitive.NewObjectID()
+	category.Ancestors = path
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	_, err = collection.InsertOne(ctx, category)
+	return err
+}
+
+// UpdateCategory menyimpan perubahan nama, slug, urutan dan parent. Jika parent berubah,
+// Ancestors kategori dan semua turunannya ikut diperbarui.
+func UpdateCategory(ctx context.Context, collection *mongo.Collection, category *Category) error {
+	var current Category
+	err := collection.FindOne(ctx, bson.M{"_id": category.ID}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return ErrCategoryNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	path, err := categoryPath(ctx, collection, category.ParentID)
+	if err != nil {
+		return err
+	}
+	for _, id := range path {
+		if id == category.ID {
+			return ErrCategoryCycle
+		}
+	}
+
+	category.Ancestors = path
+	category.CreatedAt = current.CreatedAt
+	category.UpdatedAt = time.Now()
+	if _, err := collection.ReplaceOne(ctx, bson.M{"_id": category.ID}, category); err != nil {
+		return err
+	}
+	if sameIDs(path, current.Ancestors) {
+		return nil
+	}
+
+	// Turunan menyimpan jalur lama di depan ancestors mereka; ganti dengan jalur baru
+	cursor, err := collection.Find(ctx, bson.M{"ancestors": category.ID})
+	if err != nil {
+		return err
+	}
+	var descendants []Category
+	if err := cursor.All(ctx, &descendants); err != nil {
+		return err
+	}
+	for _, d := range descendants {
+		ancestors := append(append([]primitive.ObjectID{}, path...), d.Ancestors[len(current.Ancestors):]...)
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": d.ID},
+			bson.M{"$set": bson.M{"ancestors": ancestors, "updated_at": category.UpdatedAt}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteCategory menghapus kategori yang tidak punya subkategori dan tidak dipakai produk
+func DeleteCategory(ctx context.Context, collection, products *mongo.Collection, id primitive.ObjectID) error {
+	children, err := collection.CountDocuments(ctx, bson.M{"parent_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	used, err := products.CountDocuments(ctx, bson.M{"category_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if children > 0 || used > 0 {
+		return ErrCategoryInUse
+	}
+	res, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// BuildCategoryTree menyusun daftar kategori menjadi pohon. Urutan anak mengikuti urutan masukan;
+// kategori yang parent-nya tidak ada dalam daftar diperlakukan sebagai akar.
+func BuildCategoryTree(categories []Category) []*CategoryNode {
+	nodes := make(map[primitive.ObjectID]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &CategoryNode{Category: c, Children: []*CategoryNode{}}
+	}
+	roots := []*CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// NormalizeTags menyeragamkan tag (huruf kecil, tanpa spasi di tepi), membuang yang kosong
+// dan duplikat, lalu mengurutkannya
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	out := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sameIDs(a, b []primitive.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Makanan & Minuman": "makanan-minuman",
+		"  Kopi Susu  ":     "kopi-susu",
+		"Kue (Basah)":       "kue-basah",
+	}
+	for name, want := range tests {
+		if got := Slugify(name); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", name, got, want)
+		}
+		if !IsValidSlug(want) {
+			t.Errorf("IsValidSlug(%q) = false", want)
+		}
+	}
+	for _, slug := range []string{"", "Kopi", "kopi--susu", "-kopi", "kopi susu"} {
+		if IsValidSlug(slug) {
+			t.Errorf("IsValidSlug(%q) = true", slug)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" Pedas", "halal", "pedas", "", "HALAL"})
+	if want := []string{"halal", "pedas"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCategoryTreeAndFacets(t *testing.T) {
+	food, drink, cake := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	categories := []Category{
+		{ID: food, Slug: "makanan", Ancestors: []primitive.ObjectID{}},
+		{ID: drink, Slug: "minuman", Ancestors: []primitive.ObjectID{}},
+		{ID: cake, Slug: "kue", ParentID: &food, Ancestors: []primitive.ObjectID{food}},
+	}
+
+	tree := BuildCategoryTree(categories)
+	if len(tree) != 2 || tree[0].ID != food || len(tree[0].Children) != 1 || tree[0].Children[0].ID != cake {
+		t.Fatalf("unexpected tree: %+v", tree)
+	}
+
+	facets := RollUpCategoryFacets(categories, []CategoryFacet{
+		{CategoryID: food, Count: 2},
+		{CategoryID: cake, Count: 3},
+		{CategoryID: primitive.NewObjectID(), Count: 7}, // kategori sudah dihapus
+	})
+	got := map[string]int{}
+	for _, f := range facets {
+		got[f.Slug] = f.Count
+	}
+	if want := map[string]int{"makanan": 5, "kue": 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestProductFilterBSON(t *testing.T) {
+	if f := (ProductFilter{}).BSON(); len(f) != 0 {
+		t.Errorf("empty filter = %v", f)
+	}
+	min := int64(1000)
+	f := ProductFilter{CategoryIDs: []primitive.ObjectID{}, Tags: []string{"pedas"}, MinPrice: &min}.BSON()
+	if _, ok := f["category_id"]; !ok {
+		t.Error("an empty category subtree must still filter by category")
+	}
+	if !reflect.DeepEqual(f["tags"], bson.M{"$all": []string{"pedas"}}) {
+		t.Errorf("tags = %v", f["tags"])
+	}
+	if _, ok := f["$expr"]; !ok {
+		t.Error("price range must use $expr on the effective price")
+	}
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrImportJobNotFound dikembalikan jika job impor tidak ada atau sudah kedaluwarsa
+var ErrImportJobNotFound = errors.New("import job not found")
+
+// Status job impor
+const (
+	ImportQueued    = "queued"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// importJobTTL adalah masa simpan job impor sejak dibuat
+const importJobTTL = 30 * 24 * time.Hour
+
+// ImportJob adalah impor katalog yang berjalan di latar belakang
+type ImportJob struct {
+	ID       primitive.ObjectID `bson:"_id" json:"_id"`
+	Format   string             `bson:"format" json:"format"`
+	FileName string             `bson:"file_name,omitempty" json:"file_name,omitempty"`
+	DryRun   bool               `bson:"dry_run" json:"dry_run"`
+	Status   string             `bson:"status" json:"status"`
+	// BytesTotal bersama BytesRead menunjukkan progres
+	BytesTotal  int64 `bson:"bytes_total" json:"bytes_total"`
+	ImportStats `bson:",inline"`
+	// Error diisi jika impor berhenti sebelum file selesai dibaca
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedBy  primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+	StartedAt  *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// Progress mengembalikan persentase file yang sudah diproses
+func (j *ImportJob) Progress() float64 {
+	if j.Status == ImportCompleted {
+		return 100
+	}
+	if j.BytesTotal <= 0 {
+		return 0
+	}
+	return float64(int(float64(j.BytesRead)/float64(j.BytesTotal)*1000)) / 10
+}
+
+// EnsureImportJobIndexes membuat TTL index agar job lama terhapus otomatis
+func EnsureImportJobIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(importJobTTL.Seconds())),
+	})
+	return err
+}
+
+// CreateImportJob menyimpan job baru dengan status queued
+func CreateImportJob(ctx context.Context, collection *mongo.Collection, job *ImportJob) error {
+	now := time.Now()
+	job.ID = primitive.NewObjectID()
+	job.Status = ImportQueued
+	job.Errors = []ImportRowError{}
+	job.CreatedAt, job.UpdatedAt = now, now
+	_, err := collection.InsertOne(ctx, job)
+	return err
+}
+
+// StartImportJob menandai job sedang berjalan
+func StartImportJob(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) error {
+	now := time.Now()
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": ImportRunning, "started_at": now, "updated_at": now}})
+	return err
+}
+
+// UpdateImportJobProgress menyimpan statistik sementara job
+func UpdateImportJobProgress(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, stats ImportStats) error {
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id},
+		bson.M{"$set": bson.M{"updated_at": time.Now(), "bytes_read": stats.BytesRead, "rows": stats.Rows,
+			"created": stats.Created, "updated": stats.Updated, "unchanged": stats.Unchanged,
+			"failed": stats.Failed, "errors": stats.Errors}})
+	return err
+}
+
+// FinishImportJob menyimpan hasil akhir job. cause tidak nil berarti impor berhenti di tengah.
+func FinishImportJob(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, stats ImportStats, cause error) error {
+	if err := UpdateImportJobProgress(ctx, collection, id, stats); err != nil {
+		return err
+	}
+	now := time.Now()
+	set := bson.M{"status": ImportCompleted, "finished_at": now, "updated_at": now}
+	if cause != nil {
+		set["status"], set["error"] = ImportFailed, cause.Error()
+	}
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// FindImportJob mengambil job berdasarkan ID
+func FindImportJob(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) (*ImportJob, error) {
+	var job ImportJob
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrImportJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// indexSets adalah semua koleksi yang punya index beserta fungsi pembuatnya
+var indexSets = []struct {
+	collection string
+	ensure     func(ctx context.Context, db *mongo.Database) error
+}{
+	{"users", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureUserIndexes(ctx, db.Collection("users"))
+	}},
+	{"action_tokens", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureActionTokenIndexes(ctx, db.Collection("action_tokens"))
+	}},
+	{"carts", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureCartIndexes(ctx, db.Collection("carts"))
+	}},
+	{"api_keys", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureAPIKeyIndexes(ctx, db.Collection("api_keys"))
+	}},
+	{"product_lists", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureListIndexes(ctx, db.Collection("product_lists"))
+	}},
+	{"categories", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureCategoryIndexes(ctx, db.Collection("categories"))
+	}},
+	{"products", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureProductIndexes(ctx, db.Collection("products"))
+	}},
+	{"reviews", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureReviewIndexes(ctx, db.Collection("reviews"), db.Collection("review_votes"))
+	}},
+	{"price_history", func(ctx context.Context, db *mongo.Database) error {
+		return EnsurePriceHistoryIndexes(ctx, db.Collection("price_history"))
+	}},
+	{"import_jobs", func(ctx context.Context, db *mongo.Database) error {
+		return EnsureImportJobIndexes(ctx, db.Collection("import_jobs"))
+	}},
+}
+
+// IndexedCollections mengembalikan nama koleksi yang index-nya dibuat EnsureIndexes
+func IndexedCollections() []string {
+	names := make([]string, len(indexSets))
+	for i, set := range indexSets {
+		names[i] = set.collection
+	}
+	return names
+}
+
+// EnsureIndexes membuat semua index yang dibutuhkan aplikasi. Aman dijalankan berulang kali.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	for _, set := range indexSets {
+		if err := set.ensure(ctx, db); err != nil {
+			return fmt.Errorf("index %s: %w", set.collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Jenis daftar produk milik pengguna, disimpan terpisah dari keranjang
+const (
+	ListWishlist = "wishlist"
+	ListSaved    = "saved"
+)
+
+// ListItem adalah satu produk di wishlist atau daftar "saved for later"
+type ListItem struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"-" bson:"user_id"`
+	List      string             `json:"list" bson:"list"`
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	// VariantID kosong untuk produk tanpa varian atau wishlist tingkat produk
+	VariantID primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
+	// Quantity dipakai daftar saved agar jumlah kembali sama saat dipindah ke keranjang
+	Quantity int `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	// NotifyPriceDrop mengaktifkan email saat harga produk wishlist turun
+	NotifyPriceDrop bool `json:"notify_price_drop" bson:"notify_price_drop"`
+	// NotifiedPrice adalah harga saat ditambahkan atau saat notifikasi terakhir dikirim
+	NotifiedPrice int64     `json:"-" bson:"notified_price"`
+	AddedAt       time.Time `json:"added_at" bson:"added_at"`
+}
+
+// Key mengembalikan identitas produk dan varian entri, sama seperti baris keranjang
+func (i ListItem) Key() CartLineKey {
+	return CartLineKey{ProductID: i.ProductID, VariantID: i.VariantID}
+}
+
+// listFilter mencari satu entri daftar milik pengguna
+func listFilter(userID primitive.ObjectID, list string, key CartLineKey) bson.M {
+	filter := key.match()
+	filter["user_id"] = userID
+	filter["list"] = list
+	return filter
+}
+
+// EnsureListIndexes membuat index unik per (pengguna, daftar, produk, varian) dan index untuk pemindai harga
+func EnsureListIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "list", Value: 1}, {Key: "product_id", Value: 1}, {Key: "variant_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "product_id", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"notify_price_drop": true}),
+		},
+	})
+	return err
+}
+
+// UpsertListItem menambahkan produk ke daftar atau memperbarui entri yang sudah ada
+func UpsertListItem(ctx context.Context, collection *mongo.Collection, item ListItem) (*ListItem, error) {
+	var saved ListItem
+	err := collection.FindOneAndUpdate(ctx,
+		listFilter(item.UserID, item.List, item.Key()),
+		bson.M{
+			"$set": bson.M{
+				"quantity":          item.Quantity,
+				"notify_price_drop": item.NotifyPriceDrop,
+				"notified_price":    item.NotifiedPrice,
+			},
+			"$setOnInsert": bson.M{"added_at": time.Now()},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// AddListItemQuantity menambahkan item.Quantity ke entri daftar (dibuat jika belum ada) secara
+// atomik, dengan jumlah akhir paling banyak maxQuantity. Dipakai saat memindahkan baris keranjang
+// ke daftar, agar jumlah yang sudah tersimpan tidak tertimpa.
+func AddListItemQuantity(ctx context.Context, collection *mongo.Collection, item ListItem, maxQuantity int) (*ListItem, error) {
+	quantity := bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$quantity", 0}}, item.Quantity}}
+	var saved ListItem
+	err := collection.FindOneAndUpdate(ctx,
+		listFilter(item.UserID, item.List, item.Key()),
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"quantity": bson.M{"$min": bson.A{quantity, maxQuantity}},
+			"added_at": bson.M{"$ifNull": bson.A{"$added_at", time.Now()}},
+		}}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// FindListItem mengambil satu entri daftar
+func FindListItem(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, list string, key CartLineKey) (*ListItem, error) {
+	var item ListItem
+	err := collection.FindOne(ctx, listFilter(userID, list, key)).Decode(&item)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// RemoveListItem menghapus produk dari daftar. Mengembalikan false jika tidak ada.
+func RemoveListItem(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, list string, key CartLineKey) (bool, error) {
+	res, err := collection.DeleteOne(ctx, listFilter(userID, list, key))
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
+
+// ListItems mengembalikan isi daftar pengguna, terbaru lebih dulu
+func ListItems(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, list string) ([]ListItem, error) {
+	cursor, err := collection.Find(ctx, bson.M{"user_id": userID, "list": list},
+		options.Find().SetSort(bson.D{{Key: "added_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	items := []ListItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// PriceWatch adalah entri wishlist dengan notifikasi aktif beserta produk dan pemiliknya
+type PriceWatch struct {
+	ListItem `bson:",inline"`
+	Product  Product `bson:"product"`
+	User     User    `bson:"user"`
+}
+
+// PriceChange mengembalikan harga produk (atau varian) saat ini dan apakah pemilik perlu diberi tahu.
+// Hanya penurunan di bawah harga acuan untuk produk yang masih tersedia yang dikirim.
+// Entri tingkat produk memakai harga "mulai dari" produk.
+func (w *PriceWatch) PriceChange() (current int64, notify bool) {
+	current, stock := w.Product.EffectivePrice(), w.Product.Stock
+	if !w.VariantID.IsZero() {
+		var err error
+		if current, stock, err = w.Product.Resolve(w.VariantID); err != nil {
+			// Varian sudah dihapus: acuan dibiarkan
+			return w.NotifiedPrice, false
+		}
+	}
+	return current, current < w.NotifiedPrice && (stock == nil || *stock > 0)
+}
+
+// FindPriceWatches mengambil semua entri wishlist dengan notifikasi aktif beserta produk dan
+// email pemiliknya. Entri yang produk atau penggunanya sudah dihapus dilewati.
+func FindPriceWatches(ctx context.Context, collection *mongo.Collection) (*mongo.Cursor, error) {
+	return collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"list": ListWishlist, "notify_price_drop": true}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "products", "localField": "product_id", "foreignField": "_id", "as": "product",
+		}}},
+		{{Key: "$unwind", Value: "$product"}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "users",
+			"let":  bson.M{"uid": "$user_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$uid"}}}},
+				bson.M{"$project": bson.M{"name": 1, "email": 1}},
+			},
+			"as": "user",
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+	})
+}
+
+// SetNotifiedPrice mengganti harga acuan entri wishlist hanya jika belum diubah proses lain
+// (compare-and-swap), sehingga dengan Prefork hanya satu proses yang mengirim notifikasi.
+func SetNotifiedPrice(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, previous, current int64) (bool, error) {
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "notified_price": previous},
+		bson.M{"$set": bson.M{"notified_price": current}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import "testing"
+
+func TestPriceWatchPriceChange(t *testing.T) {
+	zero, five := 0, 5
+	tests := []struct {
+		name     string
+		product  Product
+		notified int64
+		current  int64
+		notify   bool
+	}{
+		{"discount drop", Product{OriginalPrice: 100, DiscountPrice: 80}, 90, 80, true},
+		{"no discount uses original", Product{OriginalPrice: 100}, 120, 100, true},
+		{"unchanged", Product{OriginalPrice: 100, DiscountPrice: 90}, 90, 90, false},
+		{"price rise", Product{OriginalPrice: 100}, 90, 100, false},
+		{"out of stock", Product{OriginalPrice: 100, DiscountPrice: 50, Stock: &zero}, 90, 50, false},
+		{"in stock", Product{OriginalPrice: 100, DiscountPrice: 50, Stock: &five}, 90, 50, true},
+	}
+	for _, tt := range tests {
+		w := PriceWatch{ListItem: ListItem{NotifiedPrice: tt.notified}, Product: tt.product}
+		current, notify := w.PriceChange()
+		if current != tt.current || notify != tt.notify {
+			t.Errorf("%s: got (%d, %v), want (%d, %v)", tt.name, current, notify, tt.current, tt.notify)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecoveryCodeCount adalah jumlah recovery code yang diterbitkan setiap kali 2FA diaktifkan
+const RecoveryCodeCount = 10
+
+// mfaPolicyID adalah _id dokumen kebijakan 2FA di koleksi settings
+const mfaPolicyID = "mfa_policy"
+
+// MFAPolicy menentukan role yang wajib memakai 2FA. Diatur admin saat aplikasi berjalan.
+type MFAPolicy struct {
+	RequiredRoles []string `json:"required_roles" bson:"required_roles"`
+}
+
+// Requires melaporkan apakah role wajib memakai 2FA
+func (p MFAPolicy) Requires(role string) bool {
+	for _, r := range p.RequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMFAPolicy membaca kebijakan 2FA; kebijakan kosong jika belum pernah diatur
+func GetMFAPolicy(ctx context.Context, collection *mongo.Collection) (MFAPolicy, error) {
+	var policy MFAPolicy
+	err := collection.FindOne(ctx, bson.M{"_id": mfaPolicyID}).Decode(&policy)
+	if err == mongo.ErrNoDocuments {
+		return MFAPolicy{}, nil
+	}
+	return policy, err
+}
+
+// SetMFAPolicy menyimpan kebijakan 2FA
+func SetMFAPolicy(ctx context.Context, collection *mongo.Collection, policy MFAPolicy) error {
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"_id": mfaPolicyID},
+		bson.M{"$set": bson.M{"required_roles": policy.RequiredRoles}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GenerateRecoveryCodes membuat recovery code acak (format xxxxx-xxxxx) beserta hash-nya.
+// Kode asli hanya ditampilkan sekali ke pengguna; yang disimpan hanya hash.
+func GenerateRecoveryCodes() (codes []string, hashes []string, err error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode menormalkan kode (huruf kecil, tanpa spasi dan tanda hubung) lalu meng-hash-nya
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	return HashToken(code)
+}
+
+// BeginMFAEnrollment menyimpan secret yang menunggu konfirmasi
+func BeginMFAEnrollment(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, secret string) error {
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"mfa_pending_secret": secret}})
+	return err
+}
+
+// EnableMFA mengaktifkan 2FA dengan secret yang sudah dikonfirmasi beserta hash recovery code
+func EnableMFA(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, secret string, step int64, recoveryHashes []string) error {
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$set": bson.M{
+			"mfa_enabled":        true,
+			"mfa_secret":         secret,
+			"mfa_last_step":      step,
+			"mfa_recovery_codes": recoveryHashes,
+		},
+		"$unset": bson.M{"mfa_pending_secret": ""},
+	})
+	return err
+}
+
+// DisableMFA menghapus semua data 2FA pengguna
+func DisableMFA(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID) error {
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$unset": bson.M{
+		"mfa_enabled":        "",
+		"mfa_secret":         "",
+		"mfa_pending_secret": "",
+		"mfa_last_step":      "",
+		"mfa_recovery_codes": "",
+	}})
+	return err
+}
+
+// ReplaceRecoveryCodes mengganti semua recovery code dengan yang baru
+func ReplaceRecoveryCodes(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, recoveryHashes []string) error {
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"mfa_recovery_codes": recoveryHashes}})
+	return err
+}
+
+// ClaimTOTPStep menandai langkah TOTP sudah dipakai. Mengembalikan false jika langkah
+// tersebut (atau yang lebih baru) sudah pernah dipakai, sehingga kode tidak bisa diputar ulang.
+func ClaimTOTPStep(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, step int64) (bool, error) {
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "$or": bson.A{
+			bson.M{"mfa_last_step": bson.M{"$lt": step}},
+			bson.M{"mfa_last_step": bson.M{"$exists": false}},
+		}},
+		bson.M{"$set": bson.M{"mfa_last_step": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ConsumeRecoveryCode menghapus recovery code secara atomik. Mengembalikan false jika kode tidak valid.
+func ConsumeRecoveryCode(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, code string) (bool, error) {
+	hash := HashRecoveryCode(code)
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "mfa_recovery_codes": hash},
+		bson.M{"$pull": bson.M{"mfa_recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration adalah perubahan data satu kali. Run mengembalikan jumlah dokumen yang diubah,
+// atau yang akan diubah jika dryRun, tanpa menulis apa pun.
+type Migration struct {
+	ID          string
+	Description string
+	Run         func(ctx context.Context, db *mongo.Database, dryRun bool) (int64, error)
+}
+
+// Migrations adalah semua migrasi data, dijalankan berurutan. Migrasi baru ditambahkan di akhir
+// dan ID yang sudah dirilis tidak boleh diubah.
+var Migrations = []Migration{
+	{
+		ID:          "0001_backfill_product_sku",
+		Description: "give products without a sku the sku P-<ID> so they can be matched by catalog imports",
+		Run:         backfillProductSKU,
+	},
+	{
+		ID:          "0002_recompute_rating_summaries",
+		Description: "rebuild the rating summary of every reviewed product from its visible reviews",
+		Run:         recomputeRatingSummaries,
+	},
+	{
+		ID:          "0003_normalize_user_emails",
+		Description: "store user emails lowercased and trimmed (see NormalizeEmail) and report addresses shared by several users",
+		Run:         normalizeUserEmails,
+	},
+}
+
+// AppliedMigration adalah catatan migrasi yang sudah dijalankan di koleksi migrations
+type AppliedMigration struct {
+	ID        string     `bson:"_id"`
+	StartedAt time.Time  `bson:"started_at"`
+	AppliedAt *time.Time `bson:"applied_at,omitempty"`
+	Affected  int64      `bson:"affected"`
+}
+
+// PendingMigration adalah migrasi yang belum selesai. StartedAt diisi jika migrasi sudah diklaim
+// tetapi belum punya applied_at: prosesnya masih berjalan, atau berhenti di tengah jalan (crash)
+// sehingga migrasi macet sampai diambil alih dengan TakeOverMigration.
+type PendingMigration struct {
+	Migration
+	StartedAt *time.Time
+}
+
+// Stuck melaporkan apakah migrasi sudah diklaim proses lain tetapi belum selesai
+func (p PendingMigration) Stuck() bool {
+	return p.StartedAt != nil
+}
+
+// PendingMigrations mengembalikan migrasi yang belum tercatat selesai, sesuai urutan Migrations
+func PendingMigrations(ctx context.Context, collection *mongo.Collection) ([]PendingMigration, error) {
+	cursor, err := collection.Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"_id": 1, "started_at": 1, "applied_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var records []AppliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]AppliedMigration, len(records))
+	for _, r := range records {
+		recorded[r.ID] = r
+	}
+	var pending []PendingMigration
+	for _, m := range Migrations {
+		r, ok := recorded[m.ID]
+		switch {
+		case !ok:
+			pending = append(pending, PendingMigration{Migration: m})
+		case r.AppliedAt == nil:
+			startedAt := r.StartedAt
+			pending = append(pending, PendingMigration{Migration: m, StartedAt: &startedAt})
+		}
+	}
+	return pending, nil
+}
+
+// ApplyMigration menjalankan satu migrasi dan mencatatnya. Catatan dibuat sebelum migrasi berjalan
+// sehingga dua proses tidak menjalankan migrasi yang sama; catatan dihapus lagi jika migrasi gagal.
+// Mengembalikan applied false jika migrasi sudah diklaim proses lain.
+func ApplyMigration(ctx context.Context, db *mongo.Database, collection *mongo.Collection, m Migration) (affected int64, applied bool, err error) {
+	startedAt := time.Now().Truncate(time.Millisecond)
+	_, err = collection.InsertOne(ctx, AppliedMigration{ID: m.ID, StartedAt: startedAt})
+	if mongo.IsDuplicateKeyError(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return runClaimedMigration(ctx, db, collection, m, startedAt)
+}
+
+// TakeOverMigration menjalankan ulang migrasi yang macet (p.Stuck). Klaim hanya diambil alih jika
+// catatannya masih sama dengan yang dibaca PendingMigrations, sehingga dua proses yang mengambil
+// alih bersamaan tidak sama-sama menjalankannya. Mengembalikan applied false jika klaim sudah
+// berubah. Pemanggil bertanggung jawab memastikan proses yang mengklaim sebelumnya sudah berhenti.
+func TakeOverMigration(ctx context.Context, db *mongo.Database, collection *mongo.Collection, p PendingMigration) (affected int64, applied bool, err error) {
+	if !p.Stuck() {
+		return ApplyMigration(ctx, db, collection, p.Migration)
+	}
+	startedAt := time.Now().Truncate(time.Millisecond)
+	res, err := collection.UpdateOne(ctx,
+		bson.M{"_id": p.ID, "started_at": *p.StartedAt, "applied_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"started_at": startedAt}})
+	if err != nil {
+		return 0, false, err
+	}
+	if res.ModifiedCount == 0 {
+		return 0, false, nil
+	}
+	return runClaimedMigration(ctx, db, collection, p.Migration, startedAt)
+}
+
+// runClaimedMigration menjalankan migrasi yang sudah diklaim pada startedAt. Catatan hanya
+// diubah selama klaimnya masih milik proses ini.
+func runClaimedMigration(ctx context.Context, db *mongo.Database, collection *mongo.Collection, m Migration, startedAt time.Time) (affected int64, applied bool, err error) {
+	claim := bson.M{"_id": m.ID, "started_at": startedAt}
+	affected, err = m.Run(ctx, db, false)
+	if err != nil {
+		if _, delErr := collection.DeleteOne(context.WithoutCancel(ctx), claim); delErr != nil {
+			return affected, false, delErr
+		}
+		return affected, false, err
+	}
+	res, err := collection.UpdateOne(ctx, claim,
+		bson.M{"$set": bson.M{"applied_at": time.Now(), "affected": affected}})
+	if err != nil {
+		return affected, false, err
+	}
+	if res.MatchedCount == 0 {
+		return affected, false, errors.New("migration claim was taken over by another process while running")
+	}
+	return affected, true, nil
+}
+
+func backfillProductSKU(ctx context.Context, db *mongo.Database, dryRun bool) (int64, error) {
+	products := db.Collection("products")
+	filter := bson.M{"sku": bson.M{"$exists": false}}
+	if dryRun {
+		return products.CountDocuments(ctx, filter)
+	}
+	res, err := products.UpdateMany(ctx, filter, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"sku": bson.M{"$concat": bson.A{"P-", bson.M{"$toUpper": bson.M{"$toString": "$_id"}}}}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func recomputeRatingSummaries(ctx context.Context, db *mongo.Database, dryRun bool) (int64, error) {
+	reviews, products := db.Collection("reviews"), db.Collection("products")
+	ids, err := reviews.Distinct(ctx, "product_id", bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return int64(len(ids)), nil
+	}
+	for _, id := range ids {
+		productID, ok := id.(primitive.ObjectID)
+		if !ok {
+			continue
+		}
+		if _, err := RecomputeRatingSummary(ctx, reviews, products, productID); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(ids)), nil
+}
+
+// maxReportedDuplicates membatasi jumlah email duplikat yang disebut dalam pesan error
+const maxReportedDuplicates = 20
+
+// planEmailNormalization menentukan email baru untuk setiap pengguna yang emailnya belum
+// dinormalisasi. Pengguna yang emailnya sama setelah dinormalisasi tidak diubah dan
+// dikembalikan di duplicates, dikelompokkan per email yang sudah dinormalisasi.
+func planEmailNormalization(users []User) (updates map[primitive.ObjectID]string, duplicates map[string][]User) {
+	groups := make(map[string][]User, len(users))
+	for _, u := range users {
+		email := NormalizeEmail(u.Email)
+		groups[email] = append(groups[email], u)
+	}
+	updates = make(map[primitive.ObjectID]string)
+	duplicates = make(map[string][]User)
+	for email, group := range groups {
+		if len(group) > 1 {
+			duplicates[email] = group
+		} else if group[0].Email != email {
+			updates[group[0].ID] = email
+		}
+	}
+	return updates, duplicates
+}
+
+// normalizeUserEmails menormalisasi email yang tidak bentrok. Email yang dipakai lebih dari satu
+// pengguna tidak bisa diselesaikan otomatis (akun mana yang dipertahankan harus diputuskan
+// manusia), jadi migrasi gagal dengan daftar duplikatnya dan bisa dijalankan ulang setelah
+// akun-akun itu digabung atau diganti emailnya. Selama ada duplikat, index unik email tidak bisa dibuat.
+func normalizeUserEmails(ctx context.Context, db *mongo.Database, dryRun bool) (int64, error) {
+	users := db.Collection("users")
+	cursor, err := users.Find(ctx, bson.M{"email": bson.M{"$type": "string"}},
+		options.Find().SetProjection(bson.M{"_id": 1, "email": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var all []User
+	if err := cursor.All(ctx, &all); err != nil {
+		return 0, err
+	}
+
+	updates, duplicates := planEmailNormalization(all)
+	var updated int64
+	if dryRun {
+		updated = int64(len(updates))
+	} else {
+		for id, email := range updates {
+			res, err := users.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"email": email}})
+			if err != nil {
+				return updated, fmt.Errorf("user %s: %w", id.Hex(), err)
+			}
+			updated += res.ModifiedCount
+		}
+	}
+	if len(duplicates) > 0 {
+		return updated, duplicateEmailsError(duplicates)
+	}
+	return updated, nil
+}
+
+func duplicateEmailsError(duplicates map[string][]User) error {
+	emails := make([]string, 0, len(duplicates))
+	for email := range duplicates {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	var lines []string
+	for _, email := range emails {
+		if len(lines) == maxReportedDuplicates {
+			lines = append(lines, fmt.Sprintf("... and %d more", len(emails)-maxReportedDuplicates))
+			break
+		}
+		ids := make([]string, len(duplicates[email]))
+		for i, u := range duplicates[email] {
+			ids[i] = u.ID.Hex()
+		}
+		lines = append(lines, fmt.Sprintf("%s (users %s)", email, strings.Join(ids, ", ")))
+	}
+	return fmt.Errorf("%d emails are shared by several users; merge or rename these accounts and run the migration again: %s",
+		len(emails), strings.Join(lines, "; "))
+}
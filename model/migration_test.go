@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMigrationsOrdered(t *testing.T) {
+	seen := map[string]bool{}
+	for i, m := range Migrations {
+		if m.ID == "" || m.Run == nil {
+			t.Fatalf("migration %d is incomplete", i)
+		}
+		if seen[m.ID] {
+			t.Errorf("duplicate migration %s", m.ID)
+		}
+		seen[m.ID] = true
+		if i > 0 && m.ID <= Migrations[i-1].ID {
+			t.Errorf("migration %s is out of order after %s", m.ID, Migrations[i-1].ID)
+		}
+	}
+}
+
+func TestPlanEmailNormalization(t *testing.T) {
+	mixed, lower, dupA, dupB := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	updates, duplicates := planEmailNormalization([]User{
+		{ID: mixed, Email: " Budi@Example.com"},
+		{ID: lower, Email: "sari@example.com"},
+		{ID: dupA, Email: "Andi@example.com"},
+		{ID: dupB, Email: "andi@example.com"},
+	})
+	if len(updates) != 1 || updates[mixed] != "budi@example.com" {
+		t.Errorf("updates = %v", updates)
+	}
+	if group := duplicates["andi@example.com"]; len(duplicates) != 1 || len(group) != 2 {
+		t.Errorf("duplicates = %v", duplicates)
+	}
+	if err := duplicateEmailsError(duplicates); !strings.Contains(err.Error(), dupA.Hex()) || !strings.Contains(err.Error(), dupB.Hex()) {
+		t.Errorf("error does not name both users: %v", err)
+	}
+}
+
+func TestPendingMigrationsReportsStuck(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("stuck", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "test.migrations", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: Migrations[0].ID}, {Key: "started_at", Value: started}, {Key: "applied_at", Value: started}},
+			bson.D{{Key: "_id", Value: Migrations[1].ID}, {Key: "started_at", Value: started}},
+		))
+		pending, err := PendingMigrations(context.Background(), mt.Coll)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pending) != len(Migrations)-1 || pending[0].ID != Migrations[1].ID {
+			t.Fatalf("pending = %+v", pending)
+		}
+		if !pending[0].Stuck() || !pending[0].StartedAt.Equal(started) {
+			t.Errorf("%s: StartedAt = %v, want stuck since %v", pending[0].ID, pending[0].StartedAt, started)
+		}
+		for _, p := range pending[1:] {
+			if p.Stuck() {
+				t.Errorf("%s has no record but is reported stuck", p.ID)
+			}
+		}
+	})
+}
+
+func TestTakeOverMigration(t *testing.T) {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	runs := 0
+	stuck := PendingMigration{
+		Migration: Migration{ID: "9999_test", Run: func(ctx context.Context, db *mongo.Database, dryRun bool) (int64, error) {
+			runs++
+			return 7, nil
+		}},
+		StartedAt: &started,
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("claimed", func(mt *mtest.T) {
+		runs = 0
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		affected, applied, err := TakeOverMigration(context.Background(), mt.DB, mt.Coll, stuck)
+		if err != nil || !applied || affected != 7 || runs != 1 {
+			t.Fatalf("affected = %d, applied = %v, err = %v, runs = %d", affected, applied, err, runs)
+		}
+		// Klaim diambil alih hanya jika started_at masih sama dengan yang dibaca
+		claim := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q")
+		if got := claim.Document().Lookup("started_at").Time(); !got.Equal(started) {
+			t.Errorf("takeover filter started_at = %v, want %v", got, started)
+		}
+	})
+
+	mt.Run("lost race", func(mt *mtest.T) {
+		runs = 0
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+		_, applied, err := TakeOverMigration(context.Background(), mt.DB, mt.Coll, stuck)
+		if err != nil || applied || runs != 0 {
+			t.Fatalf("applied = %v, err = %v, runs = %d", applied, err, runs)
+		}
+	})
+}
@@ -0,0 +1,389 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Error perubahan harga dan jadwal diskon
+var (
+	ErrPriceConflict = errors.New("product has been modified")
+	ErrInvalidPrice  = errors.New("original_price must be greater than zero and discount_price must be between 0 and original_price")
+	ErrSaleActive    = errors.New("price cannot be changed while a sale is active")
+	ErrSaleOverlap   = errors.New("sale overlaps another scheduled or active sale")
+	ErrSaleNotFound  = errors.New("sale not found")
+	ErrSaleFinished  = errors.New("sale has already ended or been cancelled")
+)
+
+// Status jadwal diskon
+const (
+	SaleScheduled = "scheduled"
+	SaleActive    = "active"
+	SaleEnded     = "ended"
+	SaleCancelled = "cancelled"
+)
+
+// Sumber perubahan harga di riwayat
+const (
+	PriceSourceManual     = "manual"
+	PriceSourceSaleStart  = "sale_start"
+	PriceSourceSaleEnd    = "sale_end"
+	PriceSourceSaleCancel = "sale_cancel"
+	PriceSourceImport     = "import"
+)
+
+// Sale adalah diskon terjadwal untuk produk tanpa varian atau untuk satu varian. Selama
+// [StartsAt, EndsAt) harga diskon produk atau varian adalah DiscountPrice.
+type Sale struct {
+	ID primitive.ObjectID `bson:"_id" json:"_id"`
+	// VariantID kosong berarti diskon untuk produk tanpa varian
+	VariantID     primitive.ObjectID `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	DiscountPrice int64              `bson:"discount_price" json:"discount_price"`
+	StartsAt      time.Time          `bson:"starts_at" json:"starts_at"`
+	EndsAt        time.Time          `bson:"ends_at" json:"ends_at"`
+	Status        string             `bson:"status" json:"status"`
+	// PreviousDiscountPrice adalah discount_price sebelum diskon diterapkan, dikembalikan saat diskon selesai
+	PreviousDiscountPrice int64              `bson:"previous_discount_price" json:"previous_discount_price"`
+	CreatedBy             primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt             time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// pending melaporkan apakah diskon belum selesai atau dibatalkan
+func (s *Sale) pending() bool {
+	return s.Status == SaleScheduled || s.Status == SaleActive
+}
+
+// covers melaporkan apakah now berada di dalam jendela diskon
+func (s *Sale) covers(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// PriceChange adalah satu entri riwayat harga produk atau varian
+type PriceChange struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	ProductID        primitive.ObjectID `bson:"product_id" json:"product_id"`
+	VariantID        primitive.ObjectID `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	OldDiscountPrice int64              `bson:"old_discount_price" json:"old_discount_price"`
+	OldOriginalPrice int64              `bson:"old_original_price" json:"old_original_price"`
+	NewDiscountPrice int64              `bson:"new_discount_price" json:"new_discount_price"`
+	NewOriginalPrice int64              `bson:"new_original_price" json:"new_original_price"`
+	Source           string             `bson:"source" json:"source"`
+	SaleID           primitive.ObjectID `bson:"sale_id,omitempty" json:"sale_id,omitempty"`
+	// ChangedBy kosong untuk perubahan oleh penjadwal atau impor lewat CLI
+	ChangedBy primitive.ObjectID `bson:"changed_by,omitempty" json:"changed_by,omitempty"`
+	APIKeyID  primitive.ObjectID `bson:"api_key_id,omitempty" json:"api_key_id,omitempty"`
+	ChangedAt time.Time          `bson:"changed_at" json:"changed_at"`
+}
+
+// PriceActor adalah pelaku perubahan harga: pengguna dan, jika lewat API key, key-nya
+type PriceActor struct {
+	UserID   primitive.ObjectID
+	APIKeyID primitive.ObjectID
+}
+
+// EnsurePriceHistoryIndexes membuat index riwayat harga per produk
+func EnsurePriceHistoryIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "changed_at", Value: -1}},
+	})
+	return err
+}
+
+// discountAt mengembalikan harga diskon produk atau varian yang berlaku pada now. Diskon yang
+// jendelanya sedang berjalan selalu berlaku, dan diskon yang sudah lewat tetapi belum dikembalikan
+// penjadwal memakai harga sebelumnya, sehingga harga tetap benar di antara dua putaran penjadwal.
+func (p *Product) discountAt(variantID primitive.ObjectID, stored int64, now time.Time) int64 {
+	for i := range p.Sales {
+		s := &p.Sales[i]
+		if s.VariantID != variantID || !s.pending() {
+			continue
+		}
+		if s.covers(now) {
+			return s.DiscountPrice
+		}
+		if s.Status == SaleActive && !now.Before(s.EndsAt) && stored == s.DiscountPrice {
+			return s.PreviousDiscountPrice
+		}
+	}
+	return stored
+}
+
+// variantPriceAt mengembalikan harga yang dibayar untuk varian pada now
+func (p *Product) variantPriceAt(v *ProductVariant, now time.Time) int64 {
+	return payable(p.discountAt(v.ID, v.DiscountPrice, now), v.OriginalPrice)
+}
+
+func payable(discount, original int64) int64 {
+	if discount > 0 {
+		return discount
+	}
+	return original
+}
+
+// prices mengembalikan pointer ke harga diskon dan harga asli produk atau varian
+func (p *Product) prices(variantID primitive.ObjectID) (discount, original *int64, err error) {
+	if variantID.IsZero() {
+		if p.HasVariants() {
+			return nil, nil, ErrVariantRequired
+		}
+		return &p.DiscountPrice, &p.OriginalPrice, nil
+	}
+	v := p.Variant(variantID)
+	if v == nil {
+		return nil, nil, ErrVariantNotFound
+	}
+	return &v.DiscountPrice, &v.OriginalPrice, nil
+}
+
+// syncFromPrice menyalin harga varian termurah ke field produk, seperti PrepareVariants
+func (p *Product) syncFromPrice() {
+	var cheapest *ProductVariant
+	for i := range p.Variants {
+		if v := &p.Variants[i]; cheapest == nil || payable(v.DiscountPrice, v.OriginalPrice) < payable(cheapest.DiscountPrice, cheapest.OriginalPrice) {
+			cheapest = v
+		}
+	}
+	if cheapest != nil {
+		p.DiscountPrice, p.OriginalPrice = cheapest.DiscountPrice, cheapest.OriginalPrice
+	}
+}
+
+// setStoredPrice mengubah harga tersimpan dan mengembalikan entri riwayatnya (nil jika tidak berubah)
+func (p *Product) setStoredPrice(variantID primitive.ObjectID, discount, original int64, source string, now time.Time) (*PriceChange, error) {
+	d, o, err := p.prices(variantID)
+	if err != nil {
+		return nil, err
+	}
+	if *d == discount && *o == original {
+		return nil, nil
+	}
+	change := &PriceChange{
+		ProductID:        p.ID,
+		VariantID:        variantID,
+		OldDiscountPrice: *d,
+		OldOriginalPrice: *o,
+		NewDiscountPrice: discount,
+		NewOriginalPrice: original,
+		Source:           source,
+		ChangedAt:        now,
+	}
+	*d, *o = discount, original
+	if p.HasVariants() {
+		p.syncFromPrice()
+	}
+	return change, nil
+}
+
+// SetPrice mengubah harga produk tanpa varian atau satu varian. Harga tidak bisa diubah selama
+// diskon terjadwal untuk produk atau varian itu sedang berjalan.
+func (p *Product) SetPrice(variantID primitive.ObjectID, discount, original int64, now time.Time) (*PriceChange, error) {
+	if original <= 0 || discount < 0 || discount > original {
+		return nil, ErrInvalidPrice
+	}
+	for i := range p.Sales {
+		if s := &p.Sales[i]; s.VariantID == variantID && s.pending() && (s.Status == SaleActive || s.covers(now)) {
+			return nil, ErrSaleActive
+		}
+	}
+	return p.setStoredPrice(variantID, discount, original, PriceSourceManual, now)
+}
+
+// AddSale memvalidasi dan menambahkan diskon terjadwal
+func (p *Product) AddSale(sale Sale, now time.Time) (*Sale, error) {
+	_, original, err := p.prices(sale.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case sale.DiscountPrice <= 0 || sale.DiscountPrice >= *original:
+		return nil, errors.New("discount_price must be greater than zero and less than original_price")
+	case !sale.EndsAt.After(sale.StartsAt):
+		return nil, errors.New("ends_at must be after starts_at")
+	case !sale.EndsAt.After(now):
+		return nil, errors.New("ends_at must be in the future")
+	}
+	for i := range p.Sales {
+		s := &p.Sales[i]
+		if s.VariantID == sale.VariantID && s.pending() && sale.StartsAt.Before(s.EndsAt) && s.StartsAt.Before(sale.EndsAt) {
+			return nil, ErrSaleOverlap
+		}
+	}
+	sale.ID = primitive.NewObjectID()
+	sale.Status = SaleScheduled
+	sale.PreviousDiscountPrice = 0
+	sale.CreatedAt = now
+	p.Sales = append(p.Sales, sale)
+	return &p.Sales[len(p.Sales)-1], nil
+}
+
+// ApplyDueSales menerapkan diskon yang sudah mulai dan mengembalikan harga untuk diskon yang
+// sudah selesai. Diskon yang seluruh jendelanya terlewat (misalnya server mati) langsung selesai.
+// Harga tidak dikembalikan jika sudah diubah lagi selama diskon berjalan.
+func (p *Product) ApplyDueSales(now time.Time) ([]PriceChange, error) {
+	var changes []PriceChange
+	for i := range p.Sales {
+		s := &p.Sales[i]
+		discount, original, err := p.prices(s.VariantID)
+		if err != nil {
+			// Varian sudah dihapus
+			if s.pending() {
+				s.Status = SaleCancelled
+			}
+			continue
+		}
+		switch {
+		case s.Status == SaleScheduled && !now.Before(s.EndsAt):
+			s.Status = SaleEnded
+		case s.Status == SaleScheduled && !now.Before(s.StartsAt):
+			s.Status = SaleActive
+			s.PreviousDiscountPrice = *discount
+			change, err := p.setStoredPrice(s.VariantID, s.DiscountPrice, *original, PriceSourceSaleStart, now)
+			if err != nil {
+				return nil, err
+			}
+			changes = appendChange(changes, change, s.ID)
+		case s.Status == SaleActive && !now.Before(s.EndsAt):
+			s.Status = SaleEnded
+			change, err := p.revertSale(s, PriceSourceSaleEnd, now)
+			if err != nil {
+				return nil, err
+			}
+			changes = appendChange(changes, change, s.ID)
+		}
+	}
+	return changes, nil
+}
+
+// CancelSale membatalkan diskon terjadwal. Diskon yang sedang berjalan langsung dikembalikan.
+func (p *Product) CancelSale(id primitive.ObjectID, now time.Time) (*PriceChange, error) {
+	for i := range p.Sales {
+		s := &p.Sales[i]
+		if s.ID != id {
+			continue
+		}
+		if !s.pending() {
+			return nil, ErrSaleFinished
+		}
+		active := s.Status == SaleActive
+		s.Status = SaleCancelled
+		if !active {
+			return nil, nil
+		}
+		change, err := p.revertSale(s, PriceSourceSaleCancel, now)
+		if change != nil {
+			change.SaleID = s.ID
+		}
+		return change, err
+	}
+	return nil, ErrSaleNotFound
+}
+
+// revertSale mengembalikan harga diskon sebelum s diterapkan, kecuali harga sudah diubah lagi
+func (p *Product) revertSale(s *Sale, source string, now time.Time) (*PriceChange, error) {
+	discount, original, err := p.prices(s.VariantID)
+	if err != nil || *discount != s.DiscountPrice {
+		return nil, nil
+	}
+	return p.setStoredPrice(s.VariantID, s.PreviousDiscountPrice, *original, source, now)
+}
+
+func appendChange(changes []PriceChange, change *PriceChange, saleID primitive.ObjectID) []PriceChange {
+	if change == nil {
+		return changes
+	}
+	change.SaleID = saleID
+	return append(changes, *change)
+}
+
+// SaveProductPricing menyimpan harga produk, harga varian dan jadwal diskon jika produk belum
+// diubah sejak dibaca (compare-and-swap pada updated_at), lalu mencatat changes ke riwayat harga.
+// Mengembalikan ErrPriceConflict jika produk sudah diubah request atau proses lain.
+func SaveProductPricing(ctx context.Context, products, history *mongo.Collection, p *Product, changes []PriceChange, actor PriceActor) error {
+	set := bson.M{
+		"discount_price": p.DiscountPrice,
+		"original_price": p.OriginalPrice,
+		"sales":          p.Sales,
+		"updated_at":     primitive.NewDateTimeFromTime(time.Now()),
+	}
+	// Harga varian diubah per field agar stok dan field varian lain tidak tertimpa
+	for i, v := range p.Variants {
+		prefix := "variants." + strconv.Itoa(i) + "."
+		set[prefix+"discount_price"] = v.DiscountPrice
+		set[prefix+"original_price"] = v.OriginalPrice
+	}
+	res, err := products.UpdateOne(ctx, bson.M{"_id": p.ID, "updated_at": p.UpdatedAt}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		count, err := products.CountDocuments(ctx, bson.M{"_id": p.ID}, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrProductNotFound
+		}
+		return ErrPriceConflict
+	}
+	p.UpdatedAt = set["updated_at"].(primitive.DateTime)
+	return recordPriceChanges(ctx, history, changes, actor)
+}
+
+// recordPriceChanges menulis changes ke riwayat harga atas nama actor
+func recordPriceChanges(ctx context.Context, history *mongo.Collection, changes []PriceChange, actor PriceActor) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(changes))
+	for i := range changes {
+		changes[i].ChangedBy = actor.UserID
+		changes[i].APIKeyID = actor.APIKeyID
+		docs[i] = changes[i]
+	}
+	_, err := history.InsertMany(ctx, docs)
+	return err
+}
+
+// ListPriceHistory mengembalikan satu halaman riwayat harga produk, terbaru lebih dulu
+func ListPriceHistory(ctx context.Context, history *mongo.Collection, productID primitive.ObjectID, page, limit int) ([]PriceChange, int64, error) {
+	filter := bson.M{"product_id": productID}
+	total, err := history.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	cursor, err := history.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "changed_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page-1)*limit)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	list := []PriceChange{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// FindDueSaleProducts mengambil produk yang punya diskon untuk dimulai atau diselesaikan pada now
+func FindDueSaleProducts(ctx context.Context, products *mongo.Collection, now time.Time) ([]Product, error) {
+	cursor, err := products.Find(ctx, bson.M{"sales": bson.M{"$elemMatch": bson.M{"$or": bson.A{
+		bson.M{"status": SaleScheduled, "starts_at": bson.M{"$lte": now}},
+		bson.M{"status": SaleActive, "ends_at": bson.M{"$lte": now}},
+	}}}})
+	if err != nil {
+		return nil, err
+	}
+	var list []Product
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestScheduledSaleLifecycle(t *testing.T) {
+	start := time.Date(2024, 11, 11, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	p := Product{ID: primitive.NewObjectID(), DiscountPrice: 90000, OriginalPrice: 100000}
+
+	if _, err := p.AddSale(Sale{DiscountPrice: 50000, StartsAt: start, EndsAt: end}, start.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.AddSale(Sale{DiscountPrice: 60000, StartsAt: end.Add(-time.Hour), EndsAt: end.Add(time.Hour)}, start.Add(-time.Hour)); err != ErrSaleOverlap {
+		t.Errorf("overlapping sale: err = %v", err)
+	}
+
+	// Harga mengikuti jendela diskon walaupun penjadwal belum berjalan
+	if got := p.EffectivePriceAt(start.Add(-time.Minute)); got != 90000 {
+		t.Errorf("before start = %d", got)
+	}
+	if got := p.EffectivePriceAt(start); got != 50000 {
+		t.Errorf("at start = %d", got)
+	}
+	if _, err := p.SetPrice(primitive.NilObjectID, 80000, 100000, start); err != ErrSaleActive {
+		t.Errorf("manual change during sale: err = %v", err)
+	}
+
+	changes, err := p.ApplyDueSales(start.Add(time.Minute))
+	if err != nil || len(changes) != 1 || changes[0].Source != PriceSourceSaleStart || p.DiscountPrice != 50000 {
+		t.Fatalf("start: changes = %+v, err = %v, discount = %d", changes, err, p.DiscountPrice)
+	}
+	if changes[0].OldDiscountPrice != 90000 || changes[0].SaleID != p.Sales[0].ID {
+		t.Errorf("start change = %+v", changes[0])
+	}
+	// Setelah jendela selesai tetapi sebelum dikembalikan, harga sebelumnya yang berlaku
+	if got := p.EffectivePriceAt(end); got != 90000 {
+		t.Errorf("after end = %d", got)
+	}
+
+	changes, err = p.ApplyDueSales(end)
+	if err != nil || len(changes) != 1 || changes[0].Source != PriceSourceSaleEnd || p.DiscountPrice != 90000 || p.Sales[0].Status != SaleEnded {
+		t.Fatalf("end: changes = %+v, err = %v, product = %+v", changes, err, p)
+	}
+	if changes, _ := p.ApplyDueSales(end.Add(time.Hour)); len(changes) != 0 {
+		t.Errorf("ended sale applied again: %+v", changes)
+	}
+}
+
+func TestSaleVariantsAndCancel(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	small, large := primitive.NewObjectID(), primitive.NewObjectID()
+	p := Product{Variants: []ProductVariant{
+		{ID: small, OriginalPrice: 20000},
+		{ID: large, OriginalPrice: 30000},
+	}, DiscountPrice: 0, OriginalPrice: 20000}
+
+	if _, err := p.AddSale(Sale{DiscountPrice: 10000, StartsAt: now, EndsAt: now.Add(time.Hour)}, now); err != ErrVariantRequired {
+		t.Errorf("product-level sale on variant product: err = %v", err)
+	}
+	sale, err := p.AddSale(Sale{VariantID: large, DiscountPrice: 15000, StartsAt: now, EndsAt: now.Add(time.Hour)}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saleID := sale.ID
+	if price, _, _ := p.ResolveAt(large, now); price != 15000 {
+		t.Errorf("variant price during sale = %d", price)
+	}
+	// Harga "mulai dari" ikut turun karena varian besar kini lebih murah
+	if got := p.EffectivePriceAt(now); got != 15000 {
+		t.Errorf("from price = %d", got)
+	}
+
+	if _, err := p.ApplyDueSales(now); err != nil {
+		t.Fatal(err)
+	}
+	if p.DiscountPrice != 15000 || p.OriginalPrice != 30000 {
+		t.Errorf("stored from price = %d/%d", p.DiscountPrice, p.OriginalPrice)
+	}
+
+	change, err := p.CancelSale(saleID, now.Add(time.Minute))
+	if err != nil || change == nil || change.Source != PriceSourceSaleCancel || change.NewDiscountPrice != 0 {
+		t.Fatalf("cancel: change = %+v, err = %v", change, err)
+	}
+	if p.DiscountPrice != 0 || p.OriginalPrice != 20000 {
+		t.Errorf("from price after cancel = %d/%d", p.DiscountPrice, p.OriginalPrice)
+	}
+	if _, err := p.CancelSale(saleID, now); err != ErrSaleFinished {
+		t.Errorf("second cancel: err = %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Error operasi galeri gambar
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrImageNotFound   = errors.New("image not found")
+	ErrGalleryChanged  = errors.New("gallery has been modified")
+	ErrGalleryFull     = errors.New("gallery is full")
+)
+
+// ImageFile adalah satu file hasil pemrosesan gambar (ukuran dan format tertentu)
+type ImageFile struct {
+	// Name adalah original, thumb atau medium
+	Name   string `bson:"name" json:"name"`
+	Format string `bson:"format" json:"format"`
+	URL    string `bson:"url" json:"url"`
+	Width  int    `bson:"width" json:"width"`
+	Height int    `bson:"height" json:"height"`
+	// Key adalah lokasi file di BlobStore, dipakai saat gambar dihapus
+	Key string `bson:"key" json:"-"`
+}
+
+// ProductImage adalah satu gambar di galeri produk beserta semua turunannya
+type ProductImage struct {
+	ID        primitive.ObjectID `bson:"_id" json:"_id"`
+	Alt       string             `bson:"alt,omitempty" json:"alt,omitempty"`
+	Files     []ImageFile        `bson:"files" json:"files"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CoverURL mengembalikan URL yang dipakai sebagai Product.Image: ukuran medium jika ada,
+// selain itu original, dalam format dasar (bukan WebP) agar bisa ditampilkan semua client
+func (img *ProductImage) CoverURL() string {
+	var url string
+	for _, f := range img.Files {
+		if f.Format == "webp" {
+			continue
+		}
+		if f.Name == "medium" {
+			return f.URL
+		}
+		if f.Name == "original" {
+			url = f.URL
+		}
+	}
+	return url
+}
+
+// AddProductImage menambahkan gambar ke akhir galeri selama galeri belum berisi max gambar
+func AddProductImage(ctx context.Context, collection *mongo.Collection, productID primitive.ObjectID, img ProductImage, max int) (*Product, error) {
+	var product Product
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": productID, "images." + strconv.Itoa(max-1): bson.M{"$exists": false}},
+		bson.M{
+			"$push": bson.M{"images": img},
+			"$set":  bson.M{"updated_at": primitive.NewDateTimeFromTime(time.Now())},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&product)
+	if err == mongo.ErrNoDocuments {
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": productID}, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrProductNotFound
+		}
+		return nil, ErrGalleryFull
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, syncCoverImage(ctx, collection, &product)
+}
+
+// RemoveProductImage menghapus gambar dari galeri dan mengembalikan gambar yang dihapus
+// (untuk menghapus file-nya dari BlobStore)
+func RemoveProductImage(ctx context.Context, collection *mongo.Collection, productID, imageID primitive.ObjectID) (*Product, *ProductImage, error) {
+	var before Product
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": productID, "images._id": imageID},
+		bson.M{
+			"$pull": bson.M{"images": bson.M{"_id": imageID}},
+			"$set":  bson.M{"updated_at": primitive.NewDateTimeFromTime(time.Now())},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var removed ProductImage
+	after := before
+	after.Images = nil
+	for _, img := range before.Images {
+		if img.ID == imageID {
+			removed = img
+		} else {
+			after.Images = append(after.Images, img)
+		}
+	}
+	// Product.Image dikosongkan hanya jika masih menunjuk ke gambar yang dihapus
+	if len(after.Images) == 0 && after.Image == removed.CoverURL() {
+		after.Image = ""
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": productID}, bson.M{"$set": bson.M{"image": ""}}); err != nil {
+			return nil, nil, err
+		}
+		return &after, &removed, nil
+	}
+	return &after, &removed, syncCoverImage(ctx, collection, &after)
+}
+
+// ReorderProductImages mengurutkan ulang galeri. imageIDs harus berisi tepat semua gambar di galeri;
+// jika galeri diubah request lain sejak dibaca, ErrGalleryChanged dikembalikan.
+func ReorderProductImages(ctx context.Context, collection *mongo.Collection, productID primitive.ObjectID, imageIDs []primitive.ObjectID) (*Product, error) {
+	var product Product
+	err := collection.FindOne(ctx, bson.M{"_id": productID}).Decode(&product)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	images, ok := reorderImages(product.Images, imageIDs)
+	if !ok {
+		return nil, ErrGalleryChanged
+	}
+
+	// Simpan hanya jika isi galeri masih sama dengan yang dibaca
+	current := make(bson.A, 0, len(product.Images))
+	for _, img := range product.Images {
+		current = append(current, img.ID)
+	}
+	err = collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": productID, "images._id": bson.M{"$all": current}, "images": bson.M{"$size": len(current)}},
+		bson.M{"$set": bson.M{"images": images, "updated_at": primitive.NewDateTimeFromTime(time.Now())}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&product)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrGalleryChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &product, syncCoverImage(ctx, collection, &product)
+}
+
+// reorderImages menyusun images sesuai urutan ids. ok false jika ids bukan permutasi dari images.
+func reorderImages(images []ProductImage, ids []primitive.ObjectID) ([]ProductImage, bool) {
+	if len(ids) != len(images) {
+		return nil, false
+	}
+	byID := make(map[primitive.ObjectID]ProductImage, len(images))
+	for _, img := range images {
+		byID[img.ID] = img
+	}
+	out := make([]ProductImage, 0, len(ids))
+	for _, id := range ids {
+		img, ok := byID[id]
+		if !ok {
+			return nil, false
+		}
+		delete(byID, id)
+		out = append(out, img)
+	}
+	return out, true
+}
+
+// syncCoverImage menjadikan gambar pertama di galeri sebagai Product.Image
+func syncCoverImage(ctx context.Context, collection *mongo.Collection, product *Product) error {
+	if len(product.Images) == 0 {
+		return nil
+	}
+	cover := product.Images[0].CoverURL()
+	if cover == product.Image {
+		return nil
+	}
+	product.Image = cover
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": product.ID}, bson.M{"$set": bson.M{"image": cover}})
+	return err
+}
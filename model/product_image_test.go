@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReorderImages(t *testing.T) {
+	a, b, c := ProductImage{ID: primitive.NewObjectID()}, ProductImage{ID: primitive.NewObjectID()}, ProductImage{ID: primitive.NewObjectID()}
+	images := []ProductImage{a, b, c}
+
+	got, ok := reorderImages(images, []primitive.ObjectID{c.ID, a.ID, b.ID})
+	if !ok || got[0].ID != c.ID || got[1].ID != a.ID || got[2].ID != b.ID {
+		t.Errorf("got %v, %v", got, ok)
+	}
+	for _, ids := range [][]primitive.ObjectID{
+		{a.ID, b.ID},
+		{a.ID, a.ID, b.ID},
+		{a.ID, b.ID, primitive.NewObjectID()},
+	} {
+		if _, ok := reorderImages(images, ids); ok {
+			t.Errorf("%v should be rejected", ids)
+		}
+	}
+}
+
+func TestCoverURLPrefersMediumBaseFormat(t *testing.T) {
+	img := ProductImage{Files: []ImageFile{
+		{Name: "original", Format: "jpeg", URL: "/o.jpeg"},
+		{Name: "medium", Format: "webp", URL: "/m.webp"},
+		{Name: "medium", Format: "jpeg", URL: "/m.jpeg"},
+	}}
+	if got := img.CoverURL(); got != "/m.jpeg" {
+		t.Errorf("CoverURL = %q", got)
+	}
+	img.Files = img.Files[:1]
+	if got := img.CoverURL(); got != "/o.jpeg" {
+		t.Errorf("CoverURL = %q", got)
+	}
+}
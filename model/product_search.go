@@ -0,0 +1,254 @@
+package models
+
+import (
+	"context"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultPriceBuckets adalah batas bawah rentang harga untuk facet harga
+var DefaultPriceBuckets = []int64{0, 50000, 100000, 250000, 500000, 1000000}
+
+// effectivePriceExpr menghitung harga yang dibayar di dalam pipeline, sama dengan Product.EffectivePrice
+// untuk produk tanpa varian: diskon terjadwal yang jendelanya berjalan pada $$NOW berlaku, dan diskon
+// yang sudah lewat tetapi belum dikembalikan penjadwal memakai harga sebelumnya. Produk bervarian
+// memakai harga "mulai dari" yang disimpan penjadwal.
+var effectivePriceExpr = bson.M{"$let": bson.M{
+	"vars": bson.M{"discount": bson.M{"$let": bson.M{
+		"vars": bson.M{
+			"live": productSaleExpr(bson.A{
+				bson.M{"$in": bson.A{"$$s.status", bson.A{SaleScheduled, SaleActive}}},
+				bson.M{"$lte": bson.A{"$$s.starts_at", "$$NOW"}},
+				bson.M{"$gt": bson.A{"$$s.ends_at", "$$NOW"}},
+			}),
+			"ended": productSaleExpr(bson.A{
+				bson.M{"$eq": bson.A{"$$s.status", SaleActive}},
+				bson.M{"$lte": bson.A{"$$s.ends_at", "$$NOW"}},
+				bson.M{"$eq": bson.A{"$$s.discount_price", "$discount_price"}},
+			}),
+		},
+		"in": bson.M{"$ifNull": bson.A{
+			"$$live.discount_price",
+			bson.M{"$ifNull": bson.A{"$$ended.previous_discount_price", "$discount_price"}},
+		}},
+	}}},
+	"in": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$$discount", 0}}, "$$discount", "$original_price"}},
+}}
+
+// productSaleExpr mengambil diskon tingkat produk (tanpa variant_id) pertama yang memenuhi semua conds
+func productSaleExpr(conds bson.A) bson.M {
+	return bson.M{"$arrayElemAt": bson.A{bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$sales", bson.A{}}},
+		"as":    "s",
+		"cond":  bson.M{"$and": append(bson.A{bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$$s.variant_id", nil}}, nil}}}, conds...)},
+	}}, 0}}
+}
+
+// EnsureProductIndexes membuat index untuk filter kategori dan tag, serta index unik SKU produk
+// dan SKU varian di seluruh produk
+func EnsureProductIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "category_id", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		// Dipakai penjadwal untuk mencari diskon yang perlu dimulai atau diselesaikan
+		{Keys: bson.D{{Key: "sales.status", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{
+			Keys: bson.D{{Key: "sku", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"sku": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// ProductFilter adalah filter daftar produk. Field kosong tidak membatasi.
+type ProductFilter struct {
+	// CategoryIDs berisi kategori yang dipilih beserta semua turunannya
+	CategoryIDs []primitive.ObjectID
+	// Tags harus dimiliki semua oleh produk
+	Tags     []string
+	MinPrice *int64
+	MaxPrice *int64
+}
+
+// BSON mengubah filter menjadi query MongoDB
+func (f ProductFilter) BSON() bson.M {
+	filter := bson.M{}
+	if f.CategoryIDs != nil {
+		filter["category_id"] = bson.M{"$in": f.CategoryIDs}
+	}
+	if len(f.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": f.Tags}
+	}
+	var price bson.A
+	if f.MinPrice != nil {
+		price = append(price, bson.M{"$gte": bson.A{effectivePriceExpr, *f.MinPrice}})
+	}
+	if f.MaxPrice != nil {
+		price = append(price, bson.M{"$lte": bson.A{effectivePriceExpr, *f.MaxPrice}})
+	}
+	if len(price) > 0 {
+		filter["$expr"] = bson.M{"$and": price}
+	}
+	return filter
+}
+
+// CategoryFacet adalah jumlah produk dalam satu kategori. Setelah RollUpCategoryFacets,
+// jumlahnya termasuk produk di semua subkategori.
+type CategoryFacet struct {
+	CategoryID primitive.ObjectID `json:"category_id" bson:"_id"`
+	Slug       string             `json:"slug,omitempty" bson:"-"`
+	Name       string             `json:"name,omitempty" bson:"-"`
+	Count      int                `json:"count" bson:"count"`
+}
+
+// TagFacet adalah jumlah produk dengan satu tag
+type TagFacet struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
+
+// PriceBucket adalah jumlah produk dalam rentang harga [Min, Max). Max nil untuk rentang terakhir.
+type PriceBucket struct {
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max"`
+	Count int    `json:"count"`
+}
+
+// ProductFacets adalah jumlah produk per kategori, tag dan rentang harga
+type ProductFacets struct {
+	Categories []CategoryFacet `json:"categories"`
+	Tags       []TagFacet      `json:"tags"`
+	Prices     []PriceBucket   `json:"prices"`
+}
+
+// maxTagFacets membatasi jumlah tag di facet (yang terbanyak lebih dulu)
+const maxTagFacets = 50
+
+// FindProductFacets menghitung facet untuk produk yang cocok dengan filter dalam satu pipeline $facet.
+// buckets adalah batas bawah rentang harga, terurut naik.
+func FindProductFacets(ctx context.Context, collection *mongo.Collection, filter ProductFilter, buckets []int64) (*ProductFacets, error) {
+	boundaries := bson.A{}
+	for _, b := range buckets {
+		boundaries = append(boundaries, b)
+	}
+	// Batas atas terakhir agar harga di atas bucket tertinggi tetap masuk ke rentang terakhir
+	boundaries = append(boundaries, int64(math.MaxInt64))
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter.BSON()}},
+		{{Key: "$facet", Value: bson.M{
+			"categories": bson.A{
+				bson.M{"$match": bson.M{"category_id": bson.M{"$exists": true}}},
+				bson.M{"$group": bson.M{"_id": "$category_id", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			},
+			"tags": bson.A{
+				bson.M{"$unwind": "$tags"},
+				bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": maxTagFacets},
+			},
+			"prices": bson.A{
+				bson.M{"$bucket": bson.M{
+					"groupBy":    effectivePriceExpr,
+					"boundaries": boundaries,
+					"default":    "other",
+					"output":     bson.M{"count": bson.M{"$sum": 1}},
+				}},
+			},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result []struct {
+		Categories []CategoryFacet `bson:"categories"`
+		Tags       []TagFacet      `bson:"tags"`
+		Prices     []struct {
+			Min   interface{} `bson:"_id"`
+			Count int         `bson:"count"`
+		} `bson:"prices"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	facets := &ProductFacets{
+		Categories: []CategoryFacet{},
+		Tags:       []TagFacet{},
+		Prices:     priceBuckets(buckets),
+	}
+	if len(result) == 0 {
+		return facets, nil
+	}
+	if result[0].Categories != nil {
+		facets.Categories = result[0].Categories
+	}
+	if result[0].Tags != nil {
+		facets.Tags = result[0].Tags
+	}
+	for _, p := range result[0].Prices {
+		// Harga negatif atau kosong masuk ke bucket "other" dan tidak ditampilkan
+		min, ok := p.Min.(int64)
+		if !ok {
+			continue
+		}
+		for i := range facets.Prices {
+			if facets.Prices[i].Min == min {
+				facets.Prices[i].Count = p.Count
+			}
+		}
+	}
+	return facets, nil
+}
+
+// RollUpCategoryFacets menjumlahkan facet kategori ke semua leluhurnya, sesuai filter kategori
+// yang juga mencakup turunan, dan melengkapi slug serta nama. Kategori yang sudah dihapus dilewati.
+// Hasil mengikuti urutan categories.
+func RollUpCategoryFacets(categories []Category, direct []CategoryFacet) []CategoryFacet {
+	byID := make(map[primitive.ObjectID]*Category, len(categories))
+	for i := range categories {
+		byID[categories[i].ID] = &categories[i]
+	}
+	totals := map[primitive.ObjectID]int{}
+	for _, f := range direct {
+		category, ok := byID[f.CategoryID]
+		if !ok {
+			continue
+		}
+		totals[category.ID] += f.Count
+		for _, ancestor := range category.Ancestors {
+			totals[ancestor] += f.Count
+		}
+	}
+	out := []CategoryFacet{}
+	for _, c := range categories {
+		if totals[c.ID] > 0 {
+			out = append(out, CategoryFacet{CategoryID: c.ID, Slug: c.Slug, Name: c.Name, Count: totals[c.ID]})
+		}
+	}
+	return out
+}
+
+// priceBuckets menyiapkan semua rentang harga dengan jumlah 0
+func priceBuckets(buckets []int64) []PriceBucket {
+	out := make([]PriceBucket, len(buckets))
+	for i, min := range buckets {
+		out[i].Min = min
+		if i+1 < len(buckets) {
+			max := buckets[i+1]
+			out[i].Max = &max
+		}
+	}
+	return out
+}
@@ -1,10 +1,10 @@
 package models
 
 import (
-    "go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Region struct {
-    ID   primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-    Name string             `json:"name,omitempty" bson:"name,omitempty"`
-}
\ No newline at end of file
+	ID   primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name string             `json:"name,omitempty" bson:"name,omitempty"`
+}
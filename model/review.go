@@ -0,0 +1,387 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Error operasi ulasan
+var (
+	ErrReviewNotFound  = errors.New("review not found")
+	ErrReviewExists    = errors.New("review already exists")
+	ErrAlreadyVoted    = errors.New("review already voted")
+	ErrVoteNotFound    = errors.New("vote not found")
+	ErrReviewForbidden = errors.New("review belongs to another user")
+)
+
+// Status ulasan
+const (
+	ReviewPublished = "published"
+	ReviewHidden    = "hidden"
+)
+
+// Urutan daftar ulasan
+const (
+	ReviewSortNewest  = "newest"
+	ReviewSortHelpful = "helpful"
+)
+
+// ReviewReply adalah balasan merchant untuk sebuah ulasan
+type ReviewReply struct {
+	Body      string             `bson:"body" json:"body"`
+	AuthorID  primitive.ObjectID `bson:"author_id" json:"author_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Review adalah rating 1-5 dan ulasan seorang pengguna untuk satu produk
+type Review struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	ProductID  primitive.ObjectID `bson:"product_id" json:"product_id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	AuthorName string             `bson:"author_name" json:"author_name"`
+	Rating     int                `bson:"rating" json:"rating"`
+	Title      string             `bson:"title,omitempty" json:"title,omitempty"`
+	Body       string             `bson:"body,omitempty" json:"body,omitempty"`
+	// VerifiedPurchase diisi saat ulasan dibuat jika pengguna punya pesanan selesai untuk produk ini
+	VerifiedPurchase bool         `bson:"verified_purchase" json:"verified_purchase"`
+	Status           string       `bson:"status" json:"status"`
+	Flagged          bool         `bson:"flagged,omitempty" json:"flagged,omitempty"`
+	ModerationNote   string       `bson:"moderation_note,omitempty" json:"moderation_note,omitempty"`
+	HelpfulCount     int          `bson:"helpful_count" json:"helpful_count"`
+	Reply            *ReviewReply `bson:"reply,omitempty" json:"reply,omitempty"`
+	CreatedAt        time.Time    `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time    `bson:"updated_at" json:"updated_at"`
+	EditedAt         *time.Time   `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+}
+
+// RatingSummary adalah ringkasan rating produk yang didenormalisasi dari ulasan yang tampil.
+// Histogram memetakan bintang ("1" sampai "5") ke jumlah ulasan.
+type RatingSummary struct {
+	Count     int            `bson:"count" json:"count"`
+	Sum       int            `bson:"sum" json:"-"`
+	Average   float64        `bson:"average" json:"average"`
+	Histogram map[string]int `bson:"histogram" json:"histogram"`
+}
+
+// reviewVote mencatat satu suara "membantu" agar pengguna tidak bisa memberi suara dua kali
+type reviewVote struct {
+	ReviewID  primitive.ObjectID `bson:"review_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// EnsureReviewIndexes membuat index unik satu ulasan per pengguna per produk, index untuk
+// daftar ulasan, dan index unik satu suara per pengguna per ulasan
+func EnsureReviewIndexes(ctx context.Context, reviews, votes *mongo.Collection) error {
+	_, err := reviews.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "status", Value: 1}, {Key: "helpful_count", Value: -1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "flagged", Value: 1}}, Options: options.Index().SetPartialFilterExpression(bson.M{"flagged": true})},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = votes.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "review_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// HasPurchased melaporkan apakah pengguna punya pesanan selesai yang berisi produk.
+// Dokumen pesanan diharapkan berbentuk {user_id, status: "completed", items: [{product_id}]}.
+func HasPurchased(ctx context.Context, orders *mongo.Collection, userID, productID primitive.ObjectID) (bool, error) {
+	count, err := orders.CountDocuments(ctx,
+		bson.M{"user_id": userID, "status": "completed", "items.product_id": productID},
+		options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+// CreateReview menyimpan ulasan baru dan menambahkannya ke ringkasan rating produk
+func CreateReview(ctx context.Context, reviews, products *mongo.Collection, review *Review) error {
+	now := time.Now()
+	review.ID = primitive.NewObjectID()
+	review.Status = ReviewPublished
+	review.CreatedAt = now
+	review.UpdatedAt = now
+	if _, err := reviews.InsertOne(ctx, review); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrReviewExists
+		}
+		return err
+	}
+	return applyRatingDelta(ctx, products, review.ProductID, map[int]int{review.Rating: 1})
+}
+
+// UpdateReview mengubah rating dan isi ulasan milik userID, lalu menyesuaikan ringkasan rating
+func UpdateReview(ctx context.Context, reviews, products *mongo.Collection, id, userID primitive.ObjectID, rating int, title, body string) (*Review, error) {
+	now := time.Now()
+	var before Review
+	err := reviews.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "user_id": userID},
+		bson.M{"$set": bson.M{"rating": rating, "title": title, "body": body, "updated_at": now, "edited_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return nil, explainReviewMiss(ctx, reviews, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if before.Status == ReviewPublished && before.Rating != rating {
+		if err := applyRatingDelta(ctx, products, before.ProductID, map[int]int{before.Rating: -1, rating: 1}); err != nil {
+			return nil, err
+		}
+	}
+	after := before
+	after.Rating, after.Title, after.Body, after.UpdatedAt, after.EditedAt = rating, title, body, now, &now
+	return &after, nil
+}
+
+// ReplyToReview menyimpan atau mengganti balasan merchant
+func ReplyToReview(ctx context.Context, reviews *mongo.Collection, id, authorID primitive.ObjectID, body string) (*Review, error) {
+	var review Review
+	err := reviews.FindOne(ctx, bson.M{"_id": id}).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	reply := ReviewReply{Body: body, AuthorID: authorID, CreatedAt: now, UpdatedAt: now}
+	if review.Reply != nil {
+		reply.CreatedAt = review.Reply.CreatedAt
+	}
+	err = reviews.FindOneAndUpdate(ctx, bson.M{"_id": id},
+		bson.M{"$set": bson.M{"reply": reply, "updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// ModerateReview menyembunyikan atau menampilkan kembali ulasan dan menandainya (admin).
+// Ulasan yang disembunyikan tidak dihitung di ringkasan rating.
+func ModerateReview(ctx context.Context, reviews, products *mongo.Collection, id primitive.ObjectID, hidden, flagged bool, note string) (*Review, error) {
+	status := ReviewPublished
+	if hidden {
+		status = ReviewHidden
+	}
+	var before Review
+	err := reviews.FindOneAndUpdate(ctx, bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": status, "flagged": flagged, "moderation_note": note, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case before.Status == ReviewPublished && status == ReviewHidden:
+		err = applyRatingDelta(ctx, products, before.ProductID, map[int]int{before.Rating: -1})
+	case before.Status == ReviewHidden && status == ReviewPublished:
+		err = applyRatingDelta(ctx, products, before.ProductID, map[int]int{before.Rating: 1})
+	}
+	if err != nil {
+		return nil, err
+	}
+	after := before
+	after.Status, after.Flagged, after.ModerationNote = status, flagged, note
+	return &after, nil
+}
+
+// VoteHelpful mencatat suara "membantu" dari userID. Pengguna tidak bisa memilih ulasannya sendiri.
+func VoteHelpful(ctx context.Context, reviews, votes *mongo.Collection, id, userID primitive.ObjectID) (*Review, error) {
+	var review Review
+	err := reviews.FindOne(ctx, bson.M{"_id": id, "status": ReviewPublished}).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if review.UserID == userID {
+		return nil, ErrReviewForbidden
+	}
+	if _, err := votes.InsertOne(ctx, reviewVote{ReviewID: id, UserID: userID, CreatedAt: time.Now()}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrAlreadyVoted
+		}
+		return nil, err
+	}
+	return incHelpful(ctx, reviews, id, 1)
+}
+
+// UnvoteHelpful menarik kembali suara "membantu"
+func UnvoteHelpful(ctx context.Context, reviews, votes *mongo.Collection, id, userID primitive.ObjectID) (*Review, error) {
+	res, err := votes.DeleteOne(ctx, bson.M{"review_id": id, "user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	if res.DeletedCount == 0 {
+		return nil, ErrVoteNotFound
+	}
+	return incHelpful(ctx, reviews, id, -1)
+}
+
+func incHelpful(ctx context.Context, reviews *mongo.Collection, id primitive.ObjectID, delta int) (*Review, error) {
+	var review Review
+	err := reviews.FindOneAndUpdate(ctx, bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"helpful_count": delta}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// ReviewQuery adalah parameter daftar ulasan
+type ReviewQuery struct {
+	ProductID *primitive.ObjectID
+	// Status kosong berarti semua status (hanya untuk admin)
+	Status      string
+	FlaggedOnly bool
+	Rating      int
+	Sort        string
+	Page        int
+	Limit       int
+}
+
+// ListReviews mengembalikan satu halaman ulasan dan jumlah totalnya
+func ListReviews(ctx context.Context, reviews *mongo.Collection, q ReviewQuery) ([]Review, int64, error) {
+	filter := bson.M{}
+	if q.ProductID != nil {
+		filter["product_id"] = *q.ProductID
+	}
+	if q.Status != "" {
+		filter["status"] = q.Status
+	}
+	if q.FlaggedOnly {
+		filter["flagged"] = true
+	}
+	if q.Rating > 0 {
+		filter["rating"] = q.Rating
+	}
+	sort := bson.D{{Key: "created_at", Value: -1}}
+	if q.Sort == ReviewSortHelpful {
+		sort = bson.D{{Key: "helpful_count", Value: -1}, {Key: "created_at", Value: -1}}
+	}
+
+	total, err := reviews.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	cursor, err := reviews.Find(ctx, filter, options.Find().
+		SetSort(sort).
+		SetSkip(int64((q.Page-1)*q.Limit)).
+		SetLimit(int64(q.Limit)))
+	if err != nil {
+		return nil, 0, err
+	}
+	list := []Review{}
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// applyRatingDelta memperbarui ringkasan rating produk secara atomik. delta memetakan bintang ke
+// perubahan jumlah ulasan; rata-rata dihitung ulang di update yang sama.
+func applyRatingDelta(ctx context.Context, products *mongo.Collection, productID primitive.ObjectID, delta map[int]int) error {
+	count, sum := 0, 0
+	set := bson.M{}
+	for star, d := range delta {
+		if d == 0 {
+			continue
+		}
+		count += d
+		sum += star * d
+		field := "rating.histogram." + strconv.Itoa(star)
+		set[field] = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + field, 0}}, d}}
+	}
+	set["rating.count"] = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating.count", 0}}, count}}
+	set["rating.sum"] = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating.sum", 0}}, sum}}
+
+	_, err := products.UpdateOne(ctx, bson.M{"_id": productID}, mongo.Pipeline{
+		{{Key: "$set", Value: set}},
+		{{Key: "$set", Value: bson.M{"rating.average": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$rating.count", 0}},
+			bson.M{"$round": bson.A{bson.M{"$divide": bson.A{"$rating.sum", "$rating.count"}}, 2}},
+			0,
+		}}}}},
+	})
+	return err
+}
+
+// RecomputeRatingSummary menghitung ulang ringkasan rating produk dari semua ulasan yang tampil.
+// Dipakai untuk memperbaiki data jika update inkremental pernah gagal di tengah jalan.
+func RecomputeRatingSummary(ctx context.Context, reviews, products *mongo.Collection, productID primitive.ObjectID) (*RatingSummary, error) {
+	cursor, err := reviews.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"product_id": productID, "status": ReviewPublished}}},
+		{{Key: "$group", Value: bson.M{"_id": "$rating", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		Rating int `bson:"_id"`
+		Count  int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	summary := NewRatingSummary()
+	for _, g := range groups {
+		summary.add(g.Rating, g.Count)
+	}
+	_, err = products.UpdateOne(ctx, bson.M{"_id": productID}, bson.M{"$set": bson.M{"rating": summary}})
+	return summary, err
+}
+
+// NewRatingSummary mengembalikan ringkasan kosong dengan histogram lengkap 1-5
+func NewRatingSummary() *RatingSummary {
+	return &RatingSummary{Histogram: map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0}}
+}
+
+func (s *RatingSummary) add(star, count int) {
+	s.Histogram[strconv.Itoa(star)] += count
+	s.Count += count
+	s.Sum += star * count
+	s.Average = 0
+	if s.Count > 0 {
+		s.Average = float64(int(float64(s.Sum)/float64(s.Count)*100+0.5)) / 100
+	}
+}
+
+// explainReviewMiss membedakan ulasan yang tidak ada dari ulasan milik pengguna lain
+func explainReviewMiss(ctx context.Context, reviews *mongo.Collection, id primitive.ObjectID) error {
+	count, err := reviews.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrReviewNotFound
+	}
+	return ErrReviewForbidden
+}
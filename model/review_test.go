@@ -0,0 +1,18 @@
+package models
+
+import "testing"
+
+func TestRatingSummaryAdd(t *testing.T) {
+	s := NewRatingSummary()
+	if s.Count != 0 || s.Average != 0 || len(s.Histogram) != 5 {
+		t.Fatalf("empty summary = %+v", s)
+	}
+	s.add(5, 2)
+	s.add(4, 1)
+	if s.Count != 3 || s.Sum != 14 || s.Average != 4.67 {
+		t.Errorf("summary = %+v", s)
+	}
+	if s.Histogram["5"] != 2 || s.Histogram["4"] != 1 || s.Histogram["1"] != 0 {
+		t.Errorf("histogram = %v", s.Histogram)
+	}
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Tujuan token sekali pakai
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// ActionToken adalah token sekali pakai yang dikirim lewat email.
+// Hanya hash SHA-256 yang disimpan; token aslinya hanya ada di link email.
+type ActionToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Purpose   string             `bson:"purpose"`
+	TokenHash string             `bson:"token_hash"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// EnsureActionTokenIndexes membuat index lookup hash dan TTL untuk token kedaluwarsa
+func EnsureActionTokenIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+// CreateActionToken membuat token baru untuk pengguna dan mengembalikan token mentah untuk link email
+func CreateActionToken(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, purpose string, ttl time.Duration) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now()
+	_, err := collection.InsertOne(ctx, ActionToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeActionToken menandai token terpakai secara atomik. Token yang salah, kedaluwarsa
+// atau sudah dipakai menghasilkan mongo.ErrNoDocuments.
+func ConsumeActionToken(ctx context.Context, collection *mongo.Collection, token string, purpose string) (*ActionToken, error) {
+	now := time.Now()
+	var actionToken ActionToken
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"token_hash": HashToken(token),
+			"purpose":    purpose,
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"used_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&actionToken)
+	if err != nil {
+		return nil, err
+	}
+	return &actionToken, nil
+}
+
+// RevokeUserActionTokens menghapus semua token milik pengguna yang belum dipakai
+func RevokeUserActionTokens(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID) error {
+	_, err := collection.DeleteMany(ctx, bson.M{"user_id": userID, "used_at": bson.M{"$exists": false}})
+	return err
+}
+
+// HashToken mengembalikan hash SHA-256 (hex) dari token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,11 +2,22 @@ package models
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role pengguna
+const (
+	RoleUser     = "user"
+	RoleMerchant = "merchant"
+	RoleAdmin    = "admin"
 )
 
 type User struct {
@@ -14,12 +25,104 @@ type User struct {
 	Name     string             `json:"name,omitempty" bson:"name,omitempty"`
 	Email    string             `json:"email,omitempty" bson:"email,omitempty"`
 	Password string             `json:"password,omitempty" bson:"password,omitempty"`
+	Role     string             `json:"role,omitempty" bson:"role,omitempty"`
+
+	EmailVerified bool `json:"email_verified" bson:"email_verified"`
+	// TokensValidAfter: JWT yang diterbitkan sebelum waktu ini dianggap dicabut (misalnya setelah reset password)
+	TokensValidAfter *time.Time `json:"-" bson:"tokens_valid_after,omitempty"`
+
+	// Informasi penguncian akun setelah login gagal berulang
+	FailedLogins int        `json:"-" bson:"failed_logins,omitempty"`
+	LockedUntil  *time.Time `json:"-" bson:"locked_until,omitempty"`
+
+	// Two-factor authentication (TOTP)
+	MFAEnabled bool   `json:"-" bson:"mfa_enabled,omitempty"`
+	MFASecret  string `json:"-" bson:"mfa_secret,omitempty"`
+	// MFAPendingSecret menunggu konfirmasi kode pertama sebelum menjadi MFASecret
+	MFAPendingSecret string `json:"-" bson:"mfa_pending_secret,omitempty"`
+	// MFALastStep adalah langkah TOTP terakhir yang dipakai, untuk menolak kode yang diputar ulang
+	MFALastStep   int64    `json:"-" bson:"mfa_last_step,omitempty"`
+	RecoveryCodes []string `json:"-" bson:"mfa_recovery_codes,omitempty"`
+}
+
+// UserResponse adalah representasi pengguna yang aman dikirim ke client (tanpa password)
+type UserResponse struct {
+	ID            primitive.ObjectID `json:"_id"`
+	Name          string             `json:"name"`
+	Email         string             `json:"email"`
+	Role          string             `json:"role"`
+	EmailVerified bool               `json:"email_verified"`
+	MFAEnabled    bool               `json:"mfa_enabled"`
+}
+
+// Response mengubah User menjadi UserResponse
+func (u *User) Response() UserResponse {
+	return UserResponse{
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		MFAEnabled:    u.MFAEnabled,
+	}
+}
+
+// NormalizeEmail menyeragamkan email sebelum disimpan atau dicari, sehingga index unik
+// tidak bisa dilewati dengan huruf besar atau spasi
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// EnsureUserIndexes membuat index unik pada email.
+// Gagal jika database sudah berisi email duplikat; jalankan migrasi 0003_normalize_user_emails
+// (admin migrate) untuk menormalisasi email dan mendapatkan daftar duplikat yang harus dibersihkan.
+func EnsureUserIndexes(ctx context.Context, collection *mongo.Collection) error {
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("email_unique"),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w (run `admin migrate` to normalize emails and list the duplicates)", err)
+	}
+	return err
+}
+
+// IsLocked melaporkan apakah akun masih terkunci pada waktu now
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
 }
 
 type BlacklistedToken struct {
-    ID        primitive.ObjectID `bson:"_id,omitempty"`
-    Token     string             `bson:"token"`
-    CreatedAt int64              `bson:"created_at"`
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Token     string             `bson:"token"`
+	CreatedAt int64              `bson:"created_at"`
+}
+
+// ExpiredBlacklistFilter mencocokkan token yang dicabut sebelum issuedBefore. Token selalu
+// diterbitkan sebelum dicabut, jadi dengan issuedBefore = sekarang dikurangi masa berlaku JWT
+// terpanjang, semua token yang cocok sudah kedaluwarsa dan tidak perlu disimpan lagi.
+func ExpiredBlacklistFilter(issuedBefore time.Time) bson.M {
+	return bson.M{"created_at": bson.M{"$lt": issuedBefore.Unix()}}
+}
+
+// MinPasswordLength adalah panjang minimal password
+const MinPasswordLength = 8
+
+// SetPassword menyimpan hash password baru, membuka kunci akun, lalu mencabut semua JWT
+// dan token email milik pengguna
+func SetPassword(ctx context.Context, users, actionTokens *mongo.Collection, userID primitive.ObjectID, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	_, err = users.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$set":   bson.M{"password": string(hashed), "tokens_valid_after": time.Now().Truncate(time.Second)},
+		"$unset": bson.M{"failed_logins": "", "locked_until": ""},
+	})
+	if err != nil {
+		return err
+	}
+	return RevokeUserActionTokens(ctx, actionTokens, userID)
 }
 
 func CreateUser(user *User, collection *mongo.Collection) (*mongo.InsertOneResult, error) {
@@ -33,3 +136,39 @@ func FindUserByEmail(email string, collection *mongo.Collection) *mongo.SingleRe
 	defer cancel()
 	return collection.FindOne(ctx, bson.M{"email": email})
 }
+
+// RecordFailedLogin menaikkan jumlah login gagal secara atomik dan mengembalikan jumlah terbaru
+func RecordFailedLogin(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID) (int, error) {
+	var user User
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$inc": bson.M{"failed_logins": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"failed_logins": 1}),
+	).Decode(&user)
+	return user.FailedLogins, err
+}
+
+// LockUser mengunci akun sampai waktu until
+func LockUser(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID, until time.Time) error {
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"locked_until": until}})
+	return err
+}
+
+// ResetFailedLogins menghapus hitungan login gagal dan kunci akun setelah login berhasil
+func ResetFailedLogins(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID) error {
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$unset": bson.M{"failed_logins": "", "locked_until": ""}})
+	return err
+}
+
+// TokensRevokedBefore mengembalikan batas waktu penerbitan JWT yang masih berlaku untuk pengguna.
+// Nilai nol berarti tidak ada token yang dicabut.
+func TokensRevokedBefore(ctx context.Context, collection *mongo.Collection, userID primitive.ObjectID) (time.Time, error) {
+	var user User
+	err := collection.FindOne(ctx, bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"tokens_valid_after": 1}),
+	).Decode(&user)
+	if err != nil || user.TokensValidAfter == nil {
+		return time.Time{}, err
+	}
+	return *user.TokensValidAfter, nil
+}
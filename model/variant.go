@@ -0,0 +1,175 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Error resolusi varian
+var (
+	ErrVariantRequired = errors.New("variant_id is required for this product")
+	ErrVariantNotFound = errors.New("variant not found")
+)
+
+// ProductOption mendefinisikan satu dimensi varian, misalnya ukuran dengan nilai S, M, L
+type ProductOption struct {
+	Name   string   `bson:"name" json:"name"`
+	Values []string `bson:"values" json:"values"`
+}
+
+// ProductVariant adalah satu kombinasi opsi dengan SKU, harga, stok dan gambar sendiri
+type ProductVariant struct {
+	ID  primitive.ObjectID `bson:"_id" json:"_id"`
+	SKU string             `bson:"sku" json:"sku"`
+	// Options memetakan nama opsi ke nilainya, misalnya {"size": "L", "flavor": "pedas"}
+	Options       map[string]string `bson:"options" json:"options"`
+	DiscountPrice int64             `bson:"discount_price" json:"discount_price"`
+	OriginalPrice int64             `bson:"original_price" json:"original_price"`
+	// Stock nil berarti stok tidak dilacak
+	Stock *int   `bson:"stock,omitempty" json:"stock,omitempty"`
+	Image string `bson:"image,omitempty" json:"image,omitempty"`
+}
+
+// EffectivePrice mengembalikan harga tersimpan varian ini, tanpa memperhitungkan diskon terjadwal.
+// Harga yang dibayar pembeli diambil lewat Product.Resolve.
+func (v *ProductVariant) EffectivePrice() int64 {
+	return payable(v.DiscountPrice, v.OriginalPrice)
+}
+
+// HasVariants melaporkan apakah produk dijual per varian
+func (p *Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+// Variant mencari varian berdasarkan ID
+func (p *Product) Variant(id primitive.ObjectID) *ProductVariant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// Resolve menentukan harga dan stok saat ini untuk satu baris keranjang (lihat ResolveAt)
+func (p *Product) Resolve(variantID primitive.ObjectID) (price int64, stock *int, err error) {
+	return p.ResolveAt(variantID, time.Now())
+}
+
+// ResolveAt menentukan harga pada now dan stok untuk satu baris keranjang. Produk dengan varian
+// wajib menyebut varian; produk tanpa varian tidak boleh.
+func (p *Product) ResolveAt(variantID primitive.ObjectID, now time.Time) (price int64, stock *int, err error) {
+	if !p.HasVariants() {
+		if !variantID.IsZero() {
+			return 0, nil, ErrVariantNotFound
+		}
+		return p.EffectivePriceAt(now), p.Stock, nil
+	}
+	if variantID.IsZero() {
+		return 0, nil, ErrVariantRequired
+	}
+	v := p.Variant(variantID)
+	if v == nil {
+		return 0, nil, ErrVariantNotFound
+	}
+	return p.variantPriceAt(v, now), v.Stock, nil
+}
+
+// PrepareVariants memvalidasi definisi opsi dan varian, memberi ID pada varian baru, lalu
+// menyalin harga varian termurah dan total stok ke field produk. Field tingkat produk dipakai
+// untuk daftar, filter harga dan facet ("mulai dari").
+func (p *Product) PrepareVariants() error {
+	if !p.HasVariants() {
+		if len(p.Options) > 0 {
+			return errors.New("options require at least one variant")
+		}
+		return nil
+	}
+
+	values := make(map[string]map[string]bool, len(p.Options))
+	for i, option := range p.Options {
+		name := strings.TrimSpace(option.Name)
+		if name == "" || values[name] != nil {
+			return fmt.Errorf("option names must be non-empty and unique, got %q", option.Name)
+		}
+		// Nama yang sudah di-trim disimpan agar sama dengan kunci opsi varian di bawah
+		p.Options[i].Name = name
+		values[name] = map[string]bool{}
+		for _, value := range option.Values {
+			if value == "" || values[name][value] {
+				return fmt.Errorf("values of option %q must be non-empty and unique", name)
+			}
+			values[name][value] = true
+		}
+	}
+
+	skus := map[string]bool{}
+	ids := map[primitive.ObjectID]bool{}
+	combinations := map[string]bool{}
+	var cheapest *ProductVariant
+	total, tracked := 0, true
+	for i := range p.Variants {
+		v := &p.Variants[i]
+		v.SKU = strings.TrimSpace(v.SKU)
+		if v.SKU == "" || skus[v.SKU] {
+			return fmt.Errorf("variant %d: sku must be non-empty and unique", i)
+		}
+		skus[v.SKU] = true
+		if !v.ID.IsZero() {
+			if ids[v.ID] {
+				return fmt.Errorf("variant %s: id %s is used by another variant", v.SKU, v.ID.Hex())
+			}
+			ids[v.ID] = true
+		}
+		if v.OriginalPrice <= 0 || v.DiscountPrice < 0 || v.DiscountPrice > v.OriginalPrice {
+			return fmt.Errorf("variant %s: %w", v.SKU, ErrInvalidPrice)
+		}
+		if v.Stock != nil && *v.Stock < 0 {
+			return fmt.Errorf("variant %s: stock must not be negative", v.SKU)
+		}
+		trimmed := make(map[string]string, len(v.Options))
+		for name, value := range v.Options {
+			trimmed[strings.TrimSpace(name)] = value
+		}
+		v.Options = trimmed
+		if len(v.Options) != len(p.Options) {
+			return fmt.Errorf("variant %s: must set exactly one value for every option", v.SKU)
+		}
+		var key strings.Builder
+		for _, option := range p.Options {
+			value, ok := v.Options[option.Name]
+			if !ok || !values[option.Name][value] {
+				return fmt.Errorf("variant %s: invalid value for option %q", v.SKU, option.Name)
+			}
+			key.WriteString(value + "\x00")
+		}
+		if combinations[key.String()] {
+			return fmt.Errorf("variant %s: duplicate option combination", v.SKU)
+		}
+		combinations[key.String()] = true
+
+		if v.ID.IsZero() {
+			v.ID = primitive.NewObjectID()
+		}
+		if cheapest == nil || v.EffectivePrice() < cheapest.EffectivePrice() {
+			cheapest = v
+		}
+		if v.Stock == nil {
+			tracked = false
+		} else {
+			total += *v.Stock
+		}
+	}
+
+	p.DiscountPrice = cheapest.DiscountPrice
+	p.OriginalPrice = cheapest.OriginalPrice
+	p.Stock = nil
+	if tracked {
+		p.Stock = &total
+	}
+	return nil
+}
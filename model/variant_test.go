@@ -0,0 +1,98 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func variantProduct() Product {
+	s, m := 3, 0
+	return Product{
+		Options: []ProductOption{{Name: "size", Values: []string{"S", "M"}}},
+		Variants: []ProductVariant{
+			{SKU: "KAOS-S", Options: map[string]string{"size": "S"}, OriginalPrice: 100000, DiscountPrice: 90000, Stock: &s},
+			{SKU: "KAOS-M", Options: map[string]string{"size": "M"}, OriginalPrice: 80000, Stock: &m},
+		},
+	}
+}
+
+func TestPrepareVariants(t *testing.T) {
+	p := variantProduct()
+	if err := p.PrepareVariants(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Variants[0].ID.IsZero() || p.Variants[1].ID.IsZero() {
+		t.Error("variants must get IDs")
+	}
+	if p.EffectivePrice() != 80000 || p.Stock == nil || *p.Stock != 3 {
+		t.Errorf("product price = %d, stock = %v", p.EffectivePrice(), p.Stock)
+	}
+
+	invalid := map[string]func(*Product){
+		"duplicate sku":     func(p *Product) { p.Variants[1].SKU = "KAOS-S" },
+		"unknown value":     func(p *Product) { p.Variants[1].Options["size"] = "XL" },
+		"duplicate combo":   func(p *Product) { p.Variants[1].Options["size"] = "S" },
+		"missing option":    func(p *Product) { p.Variants[1].Options = map[string]string{} },
+		"no price":          func(p *Product) { p.Variants[0].OriginalPrice = 0 },
+		"discount too high": func(p *Product) { p.Variants[0].DiscountPrice = 120000 },
+		"duplicate id": func(p *Product) {
+			id := primitive.NewObjectID()
+			p.Variants[0].ID, p.Variants[1].ID = id, id
+		},
+		"options no variants": func(p *Product) { p.Variants = nil },
+	}
+	for name, mutate := range invalid {
+		p := variantProduct()
+		mutate(&p)
+		if err := p.PrepareVariants(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPrepareVariantsTrimsOptionNames(t *testing.T) {
+	p := variantProduct()
+	p.Options[0].Name = " size "
+	p.Variants[1].Options = map[string]string{"size ": "M"}
+	if err := p.PrepareVariants(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Options[0].Name != "size" || p.Variants[1].Options["size"] != "M" {
+		t.Errorf("options = %q, variant options = %v", p.Options[0].Name, p.Variants[1].Options)
+	}
+}
+
+func TestResolveAndValidateVariants(t *testing.T) {
+	p := variantProduct()
+	if err := p.PrepareVariants(); err != nil {
+		t.Fatal(err)
+	}
+	p.ID = primitive.NewObjectID()
+	small, medium := p.Variants[0].ID, p.Variants[1].ID
+
+	if _, _, err := p.Resolve(primitive.NilObjectID); err != ErrVariantRequired {
+		t.Errorf("err = %v", err)
+	}
+	if _, _, err := p.Resolve(primitive.NewObjectID()); err != ErrVariantNotFound {
+		t.Errorf("err = %v", err)
+	}
+	if price, stock, err := p.Resolve(small); err != nil || price != 90000 || *stock != 3 {
+		t.Errorf("price = %d, stock = %v, err = %v", price, stock, err)
+	}
+
+	// Dua varian produk yang sama adalah dua baris terpisah
+	ops := []CartOperation{
+		{Op: CartOpAdd, ProductID: p.ID, VariantID: small, Quantity: 2},
+		{Op: CartOpAdd, ProductID: p.ID, VariantID: medium, Quantity: 1},
+		{Op: CartOpAdd, ProductID: p.ID, VariantID: small, Quantity: 1},
+	}
+	items, errs, lastOp := ApplyCartOperations(nil, ops, 10)
+	if len(errs) != 0 || len(items) != 2 || items[0].Quantity != 3 {
+		t.Fatalf("items = %+v, errs = %+v", items, errs)
+	}
+	errs = ValidateCartProducts(items, map[primitive.ObjectID]Product{p.ID: p}, ops, lastOp)
+	if len(errs) != 1 || errs[0].Index != 1 || errs[0].VariantID != medium {
+		t.Errorf("expected stock error for the medium variant on op 1, got %+v", errs)
+	}
+}
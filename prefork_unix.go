@@ -0,0 +1,66 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// childProcesses mencatat PID child Prefork milik proses master
+type childProcesses struct {
+	mu   sync.Mutex
+	pids []int
+}
+
+func (p *childProcesses) add(pid int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pids = append(p.pids, pid)
+	return nil
+}
+
+// shutdown meneruskan SIGTERM ke semua child lalu menunggu setiap child melapor selesai
+// (SIGUSR1) atau sampai timeout habis.
+func (p *childProcesses) shutdown(timeout time.Duration) {
+	p.mu.Lock()
+	pids := append([]int(nil), p.pids...)
+	p.mu.Unlock()
+
+	done := make(chan os.Signal, len(pids))
+	signal.Notify(done, syscall.SIGUSR1)
+	defer signal.Stop(done)
+
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+			slog.Error("Gagal mengirim SIGTERM ke child", "pid", pid, "error", err)
+		}
+	}
+
+	// Sinyal yang tiba bersamaan bisa tergabung, sehingga deadline tetap menjadi batas akhir
+	deadline := time.NewTimer(timeout + time.Second)
+	defer deadline.Stop()
+	for remaining := len(pids); remaining > 0; remaining-- {
+		select {
+		case <-done:
+		case <-deadline.C:
+			slog.Warn("Child belum selesai saat batas waktu shutdown", "remaining", remaining)
+			return
+		}
+	}
+}
+
+// notifyMasterAndWait dipanggil child setelah shutdown selesai. Master Fiber mematikan semua
+// child begitu salah satunya keluar, jadi child tidak boleh keluar sendiri agar child lain yang
+// masih menyelesaikan request tidak ikut terbunuh. Child akan keluar ketika master berhenti.
+func notifyMasterAndWait() {
+	if err := syscall.Kill(os.Getppid(), syscall.SIGUSR1); err != nil {
+		slog.Error("Gagal memberi tahu proses master", "error", err)
+		return
+	}
+	select {}
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// childProcesses mencatat PID child Prefork milik proses master
+type childProcesses struct {
+	mu   sync.Mutex
+	pids []int
+}
+
+func (p *childProcesses) add(pid int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pids = append(p.pids, pid)
+	return nil
+}
+
+// shutdown di Windows tidak bisa meneruskan SIGTERM, sehingga child langsung dihentikan
+func (p *childProcesses) shutdown(time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pid := range p.pids {
+		if proc, err := os.FindProcess(pid); err == nil {
+			if err := proc.Kill(); err != nil {
+				slog.Error("Gagal menghentikan child", "pid", pid, "error", err)
+			}
+		}
+	}
+}
+
+func notifyMasterAndWait() {}
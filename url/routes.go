@@ -1,40 +1,164 @@
 package url
 
 import (
+	"context"
+	"time"
+
+	"github.com/ChekoutGobiz/BackendChekout/config"
 	controllers "github.com/ChekoutGobiz/BackendChekout/controller"
+	"github.com/ChekoutGobiz/BackendChekout/helper"
 	"github.com/ChekoutGobiz/BackendChekout/middleware"
+	models "github.com/ChekoutGobiz/BackendChekout/model"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// catalogImportPath adalah route impor katalog, satu-satunya route yang body-nya dibaca sebagai stream
+const catalogImportPath = "/api/admin/catalog/import"
+
 // SetupRoutes mendefinisikan semua rute aplikasi
-func SetupRoutes(app *fiber.App) {
+func SetupRoutes(app *fiber.App, db *mongo.Database, cfg *config.Config, keys *helper.KeySet) error {
+	// Body dibatasi untuk semua route; impor katalog membaca body-nya sendiri sebagai stream
+	app.Use(middleware.LimitBody(cfg.BodyLimit(), fiber.MethodPost+" "+catalogImportPath))
+
+	verifyJWT := middleware.VerifyJWT(keys, db.Collection("users"))
+	// Pendaftaran 2FA juga menerima token mfa_enroll dari login role yang mewajibkan 2FA
+	verifyEnrollJWT := middleware.VerifyJWT(keys, db.Collection("users"), middleware.TokenAccess, middleware.TokenMFAEnroll)
+	// Rute katalog juga bisa diakses dengan X-API-Key (dibatasi scope)
+	authenticate := middleware.Authenticate(verifyJWT, db.Collection("users"), db.Collection("api_keys"))
+
+	limiterStore, err := newLimiterStore(db, cfg.RateLimit.Store)
+	if err != nil {
+		return err
+	}
+	newLimiter := func(name string, rule config.RateLimitRule) *middleware.Limiter {
+		return &middleware.Limiter{Store: limiterStore, Name: name, Limit: rule.Limit, Window: rule.Window.Std()}
+	}
+	loginPerIP := middleware.RateLimit(newLimiter("login_ip", cfg.RateLimit.LoginPerIP), middleware.KeyByIP)
+	loginPerAccount := middleware.RateLimit(newLimiter("login_account", cfg.RateLimit.LoginPerAccount), middleware.KeyByEmail)
+	registerPerIP := middleware.RateLimit(newLimiter("register_ip", cfg.RateLimit.RegisterPerIP), middleware.KeyByIP)
+	emailPerIP := middleware.RateLimit(newLimiter("email_ip", cfg.RateLimit.EmailPerIP), middleware.KeyByIP)
+
+	// Health check routes untuk load balancer
+	app.Get("/healthz", controllers.Healthz)
+	app.Get("/readyz", controllers.Readyz)
+	app.Get("/version", controllers.Version)
+	app.Get("/.well-known/jwks.json", controllers.JWKS)
+
+	// File upload untuk store local (store s3 dilayani langsung oleh bucket atau CDN)
+	if cfg.Media.ServesLocalFiles() {
+		app.Static(cfg.Media.PublicURL, cfg.Media.LocalDir, fiber.Static{MaxAge: 86400})
+	}
+
+	// Metrik Prometheus
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+
+	// Admin routes
+	app.Get("/debug/config", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.GetConfig)
+
 	// Grouping untuk lebih rapi
 	api := app.Group("/api")
 
 	// Authentication routes
-	api.Post("/register", controllers.Register)
-	api.Post("/login", controllers.Login)
-	app.Post("/logout", controllers.Logout)  // Menambahkan route logout
+	api.Post("/register", registerPerIP, controllers.Register)
+	api.Post("/login", loginPerIP, loginPerAccount, controllers.Login)
+	api.Post("/login/mfa", loginPerIP, controllers.LoginMFA)
+	app.Post("/logout", controllers.Logout) // Menambahkan route logout
+
+	// Verifikasi email dan reset password
+	api.Post("/email/verification", emailPerIP, controllers.RequestEmailVerification)
+	api.Post("/email/verify", controllers.VerifyEmail)
+	api.Post("/password/forgot", emailPerIP, controllers.ForgotPassword)
+	api.Post("/password/reset", controllers.ResetPassword)
+
+	// Profil pengguna yang sedang login
+	api.Get("/me", verifyJWT, controllers.GetMe)
+	api.Put("/me", verifyJWT, controllers.UpdateMe)
+	api.Put("/me/password", verifyJWT, controllers.ChangePassword)
+	api.Delete("/me", verifyJWT, controllers.DeleteMe)
+
+	// Two-factor authentication (TOTP)
+	api.Post("/me/mfa/enroll", verifyEnrollJWT, controllers.EnrollMFA)
+	api.Post("/me/mfa/confirm", verifyEnrollJWT, controllers.ConfirmMFA)
+	api.Post("/me/mfa/disable", verifyJWT, controllers.DisableMFA)
+	api.Post("/me/mfa/recovery-codes", verifyJWT, controllers.RegenerateRecoveryCodes)
+	api.Get("/admin/mfa-policy", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.GetMFAPolicy)
+	api.Put("/admin/mfa-policy", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.UpdateMFAPolicy)
+
+	// API key untuk integrasi server-to-server (merchant dan admin)
+	merchantOrAdmin := middleware.RequireRole(models.RoleMerchant, models.RoleAdmin)
+	api.Post("/api-keys", verifyJWT, merchantOrAdmin, controllers.CreateAPIKey)
+	api.Get("/api-keys", verifyJWT, merchantOrAdmin, controllers.ListAPIKeys)
+	api.Delete("/api-keys/:id", verifyJWT, merchantOrAdmin, controllers.RevokeAPIKey)
 
 	// Region routes
 	api.Post("/regions", controllers.CreateRegion)
 	api.Get("/regions", controllers.GetRegions)
 
-	// Product routes - Protected by JWT middleware
-	api.Use(middleware.VerifyJWT) // Hanya proteksi rute produk
-	api.Post("/products", controllers.CreateProduct)
-	api.Get("/products", controllers.GetProducts)
+	// Product routes - Protected by JWT atau API key
+	api.Post("/products", authenticate, middleware.RequireScope(models.ScopeProductsWrite), controllers.CreateProduct)
+	api.Get("/products", authenticate, middleware.RequireScope(models.ScopeProductsRead), controllers.GetProducts)
+	// Gambar produk hanya dikelola merchant dan admin
+	api.Post("/products/:id/images", authenticate, middleware.RequireScope(models.ScopeProductsWrite), merchantOrAdmin, controllers.UploadProductImage)
+	api.Put("/products/:id/images/order", authenticate, middleware.RequireScope(models.ScopeProductsWrite), merchantOrAdmin, controllers.ReorderProductImages)
+	api.Delete("/products/:id/images/:image_id", authenticate, middleware.RequireScope(models.ScopeProductsWrite), merchantOrAdmin, controllers.DeleteProductImage)
+	api.Get("/products/facets", authenticate, middleware.RequireScope(models.ScopeProductsRead), controllers.GetProductFacets)
+
+	// Harga produk, riwayatnya dan diskon terjadwal (merchant dan admin)
+	api.Put("/products/:id/price", authenticate, middleware.RequireScope(models.ScopeProductsWrite), merchantOrAdmin, controllers.UpdateProductPrice)
+	api.Get("/products/:id/price-history", authenticate, middleware.RequireScope(models.ScopeProductsRead), merchantOrAdmin, controllers.GetPriceHistory)
+	api.Post("/products/:id/sales", authenticate, middleware.RequireScope(models.ScopeProductsWrite), merchantOrAdmin, controllers.CreateSale)
+	api.Delete("/products/:id/sales/:sale_id", authenticate, middleware.RequireScope(models.ScopeProductsWrite), merchantOrAdmin, controllers.CancelSale)
 
-	// Cart routes - Protected by JWT middleware
-	// Menambahkan rute untuk menambahkan item ke keranjang dengan verifikasi JWT
-	api.Post("/cart", middleware.VerifyJWT, controllers.AddItemToCart)
+	// Ulasan produk - dibaca seperti produk, ditulis oleh pengguna login, dibalas merchant, dimoderasi admin
+	api.Get("/products/:id/reviews", authenticate, middleware.RequireScope(models.ScopeProductsRead), controllers.GetProductReviews)
+	api.Post("/products/:id/reviews", verifyJWT, controllers.CreateReview)
+	api.Put("/reviews/:id", verifyJWT, controllers.UpdateReview)
+	api.Put("/reviews/:id/reply", verifyJWT, merchantOrAdmin, controllers.ReplyToReview)
+	api.Post("/reviews/:id/helpful", verifyJWT, controllers.VoteReviewHelpful)
+	api.Delete("/reviews/:id/helpful", verifyJWT, controllers.UnvoteReviewHelpful)
+	api.Get("/admin/reviews", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.GetModerationReviews)
+	api.Put("/admin/reviews/:id/moderation", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.ModerateReview)
 
-	// Menambahkan rute untuk mengambil keranjang pengguna dengan verifikasi JWT
-	api.Get("/cart", middleware.VerifyJWT, controllers.GetCart)
+	// Impor dan ekspor katalog (admin)
+	app.Post(catalogImportPath, verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.StartCatalogImport)
+	api.Get("/admin/catalog/import/:id", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.GetCatalogImport)
+	api.Get("/admin/catalog/export", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.ExportCatalog)
 
-	// Menambahkan rute untuk memperbarui jumlah item dalam keranjang dengan verifikasi JWT
-	api.Put("/cart", middleware.VerifyJWT, controllers.UpdateCartItem)
+	// Kategori - dibaca seperti produk, diubah hanya oleh admin
+	api.Get("/categories", authenticate, middleware.RequireScope(models.ScopeProductsRead), controllers.GetCategories)
+	api.Post("/categories", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.CreateCategory)
+	api.Put("/categories/:id", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.UpdateCategory)
+	api.Delete("/categories/:id", verifyJWT, middleware.RequireRole(models.RoleAdmin), controllers.DeleteCategory)
+
+	// Cart routes - Pengguna login (JWT) atau tamu (cart token di cookie atau header X-Cart-Token)
+	cartAuth := middleware.OptionalAuth(verifyJWT)
+	api.Post("/cart", cartAuth, controllers.AddItemToCart)
+	api.Get("/cart", cartAuth, controllers.GetCart)
+	api.Put("/cart", cartAuth, controllers.UpdateCartItem)
+	api.Patch("/cart", cartAuth, controllers.PatchCart)
+	api.Delete("/cart/:product_id", cartAuth, controllers.RemoveItemFromCart)
+	api.Post("/cart/:product_id/save", verifyJWT, controllers.SaveCartItemForLater)
+
+	// Wishlist dan daftar "saved for later" - hanya pengguna login, terpisah dari keranjang
+	api.Get("/wishlist", verifyJWT, controllers.GetList(models.ListWishlist))
+	api.Post("/wishlist", verifyJWT, controllers.AddToList(models.ListWishlist))
+	api.Delete("/wishlist/:product_id", verifyJWT, controllers.RemoveFromList(models.ListWishlist))
+	api.Get("/saved", verifyJWT, controllers.GetList(models.ListSaved))
+	api.Post("/saved", verifyJWT, controllers.AddToList(models.ListSaved))
+	api.Delete("/saved/:product_id", verifyJWT, controllers.RemoveFromList(models.ListSaved))
+	api.Post("/saved/:product_id/move-to-cart", verifyJWT, controllers.MoveSavedItemToCart)
+
+	return nil
+}
 
-	// Menambahkan rute untuk menghapus item dari keranjang dengan verifikasi JWT
-	api.Delete("/cart/:product_id", middleware.VerifyJWT, controllers.RemoveItemFromCart)
+func newLimiterStore(db *mongo.Database, store string) (middleware.LimiterStore, error) {
+	if store != config.LimiterStoreMongo {
+		return middleware.NewMemoryLimiterStore(), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return middleware.NewMongoLimiterStore(ctx, db.Collection("rate_limits"))
 }